@@ -0,0 +1,30 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/max-nicholson/advent-of-code-2024/lib/gen"
+)
+
+// Gen writes a randomly generated puzzle input for a day to stdout.
+func Gen(args []string) error {
+	flags := flag.NewFlagSet("gen", flag.ExitOnError)
+	day := flags.Int("day", 0, "The day to generate an input for")
+	size := flags.Int("size", 100, "How large the input should be; see each day's generator for what this means")
+	seed := flags.Uint64("seed", 1, "Seed for the random number generator")
+	flags.Parse(args)
+
+	if *day == 0 {
+		return fmt.Errorf("--day is required")
+	}
+
+	input, err := gen.Generate(*day, *size, *seed)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(os.Stdout, input)
+	return err
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"slices"
+)
+
+type Command func(args []string) error
+
+var commands = map[string]Command{
+	"gen": Gen,
+	"new": New,
+	"run": Run,
+}
+
+func packageDir(day int) string {
+	return fmt.Sprintf("pkg/%02d", day)
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: aoc <command> [flags]\n\ncommands:\n")
+	for _, name := range slices.Sorted(maps.Keys(commands)) {
+		fmt.Fprintf(os.Stderr, "  %s\n", name)
+	}
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	command, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+	}
+
+	if err := command(os.Args[2:]); err != nil {
+		log.Fatalf("%s: %v", os.Args[1], err)
+	}
+}
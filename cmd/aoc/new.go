@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"text/template"
+)
+
+//go:embed template/*.tmpl
+var templates embed.FS
+
+// Scaffold describes a new day to render from the templates
+type Scaffold struct {
+	Day int
+	// Lines reads the input as lines rather than a single string
+	Lines bool
+}
+
+func (s Scaffold) Dir() string {
+	return packageDir(s.Day)
+}
+
+func (s Scaffold) Input() string {
+	if s.Lines {
+		return "lines"
+	}
+	return "content"
+}
+
+func (s Scaffold) InputType() string {
+	if s.Lines {
+		return "[]string"
+	}
+	return "string"
+}
+
+// New creates the package for a day from the templates, and registers it with
+// the runner.
+func New(args []string) error {
+	flags := flag.NewFlagSet("new", flag.ExitOnError)
+	day := flags.Int("day", 0, "The day to create")
+	input := flags.String("input", "lines", "How the input is read: `lines` or file")
+	flags.Parse(args)
+
+	if *day == 0 {
+		return fmt.Errorf("--day is required")
+	}
+
+	if *input != "lines" && *input != "file" {
+		return fmt.Errorf("--input must be lines or file, got %s", *input)
+	}
+
+	s := Scaffold{Day: *day, Lines: *input == "lines"}
+	if err := scaffold(".", s); err != nil {
+		return err
+	}
+
+	log.Printf("created day %02d in %s", s.Day, s.Dir())
+	return nil
+}
+
+// scaffold renders the day into root, removing whatever it created if any of
+// it fails so it can be tried again
+func scaffold(root string, s Scaffold) (err error) {
+	if s.Day < 1 || s.Day > 25 {
+		return fmt.Errorf("day must be between 1 and 25, got %d", s.Day)
+	}
+
+	if slices.Contains(registry, s.Day) {
+		return fmt.Errorf("day %d is already registered", s.Day)
+	}
+
+	dir := filepath.Join(root, s.Dir())
+	// Mkdir rather than MkdirAll, so an existing day is never overwritten
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(dir)
+		}
+	}()
+
+	if err := os.Mkdir(filepath.Join(dir, "testdata"), 0o755); err != nil {
+		return fmt.Errorf("failed to create examples directory: %w", err)
+	}
+
+	for _, name := range []string{"main.go", "main_test.go"} {
+		if err := render(filepath.Join(dir, name), name+".tmpl", s); err != nil {
+			return err
+		}
+	}
+
+	days := slices.Sorted(slices.Values(append(slices.Clone(registry), s.Day)))
+	return render(filepath.Join(root, "cmd", "aoc", "registry.go"), "registry.go.tmpl", days)
+}
+
+func render(path string, name string, data any) error {
+	t, err := template.ParseFS(templates, "template/"+name)
+	if err != nil {
+		return fmt.Errorf("failed to parse template %s: %w", name, err)
+	}
+
+	var b bytes.Buffer
+	if err := t.Execute(&b, data); err != nil {
+		return fmt.Errorf("failed to render template %s: %w", name, err)
+	}
+
+	source, err := format.Source(b.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to format %s: %w", path, err)
+	}
+
+	if err := os.WriteFile(path, source, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRegistryMatchesPackages(t *testing.T) {
+	for _, day := range registry {
+		if _, err := os.Stat(filepath.Join("..", "..", packageDir(day), "main.go")); err != nil {
+			t.Errorf("day %d is registered: %v", day, err)
+		}
+	}
+}
+
+func TestScaffold(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "cmd", "aoc"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "pkg"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := scaffold(root, Scaffold{Day: 24, Lines: false}); err != nil {
+		t.Fatal(err)
+	}
+
+	main, err := os.ReadFile(filepath.Join(root, "pkg", "24", "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`lib.ReadFile("pkg/24/input.txt")`, "func Part1(content string) (int, error)"} {
+		if !strings.Contains(string(main), want) {
+			t.Errorf("want main.go to contain %s, got\n%s", want, main)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "pkg", "24", "testdata")); err != nil {
+		t.Errorf("want examples directory: %v", err)
+	}
+
+	registered, err := os.ReadFile(filepath.Join(root, "cmd", "aoc", "registry.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(registered), "\t23,\n\t24,\n}") {
+		t.Errorf("want day 24 registered, got\n%s", registered)
+	}
+
+	// registry is only read at compile time, so day 24 is still unknown here
+	if err := os.WriteFile(filepath.Join(root, "pkg", "24", "main.go"), []byte("keep"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := scaffold(root, Scaffold{Day: 24}); err == nil {
+		t.Errorf("want error when day already exists")
+	}
+	if b, _ := os.ReadFile(filepath.Join(root, "pkg", "24", "main.go")); string(b) != "keep" {
+		t.Errorf("want existing day left untouched, got %s", b)
+	}
+
+	if err := scaffold(root, Scaffold{Day: 1}); err == nil {
+		t.Errorf("want error when day is already registered")
+	}
+}
+
+func TestScaffoldDayOutOfRange(t *testing.T) {
+	root := t.TempDir()
+	for _, day := range []int{-3, 0, 26, 99} {
+		if err := scaffold(root, Scaffold{Day: day}); err == nil {
+			t.Errorf("scaffold() want error for day %d", day)
+		}
+	}
+
+	if entries, _ := os.ReadDir(root); len(entries) != 0 {
+		t.Errorf("want nothing created, got %v", entries)
+	}
+}
+
+func TestScaffoldCleansUpOnError(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "pkg"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// without cmd/aoc, writing the registry fails after the day is rendered
+	if err := scaffold(root, Scaffold{Day: 24}); err == nil {
+		t.Fatal("want error when the registry can't be written")
+	}
+	if _, err := os.Stat(filepath.Join(root, "pkg", "24")); !os.IsNotExist(err) {
+		t.Errorf("want half created day removed, got %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(root, "cmd", "aoc"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := scaffold(root, Scaffold{Day: 24}); err != nil {
+		t.Errorf("want retry to succeed, got %v", err)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+// registry lists every day with a solution under pkg/, and is kept up to date
+// by `aoc new`
+var registry = []int{
+	1,
+	2,
+	3,
+	4,
+	5,
+	6,
+	7,
+	8,
+	9,
+	10,
+	11,
+	12,
+	13,
+	14,
+	15,
+	16,
+	17,
+	18,
+	19,
+	20,
+	21,
+	22,
+	23,
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+)
+
+// Run runs the solution for a day, or every registered day if none is given.
+// Any arguments after the flags are passed on to the solution.
+func Run(args []string) error {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	day := flags.Int("day", 0, "The day to run; defaults to every registered day")
+	flags.Parse(args)
+
+	days := registry
+	if *day != 0 {
+		if !slices.Contains(registry, *day) {
+			return fmt.Errorf("day %d is not registered; create it with `aoc new --day %d`", *day, *day)
+		}
+		days = []int{*day}
+	}
+
+	for _, day := range days {
+		if len(days) > 1 {
+			fmt.Printf("day %02d\n", day)
+		}
+
+		cmd := exec.Command("go", append([]string{"run", "./" + packageDir(day)}, flags.Args()...)...)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("day %02d: %w", day, err)
+		}
+	}
+
+	return nil
+}
@@ -43,7 +43,7 @@ func main() {
 
 	data, err := io.ReadAll(resp.Body)
 	if err != nil {
-		log.Fatalf("failed to read body: %w", err)
+		log.Fatalf("failed to read body: %v", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
@@ -2,9 +2,9 @@ module github.com/max-nicholson/advent-of-code-2024
 
 go 1.23.4
 
-require golang.org/x/exp v0.0.0-20241204233417-43b7b7cde48d
-
 require (
-	github.com/google/go-cmp v0.6.0 // indirect
-	github.com/mowshon/iterium v1.0.0 // indirect
+	github.com/mowshon/iterium v1.0.0
+	golang.org/x/exp v0.0.0-20241204233417-43b7b7cde48d
 )
+
+require github.com/google/go-cmp v0.6.0
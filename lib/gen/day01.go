@@ -0,0 +1,21 @@
+package gen
+
+import (
+	"fmt"
+	"math/rand/v2"
+)
+
+// Day01 generates size pairs of location IDs. IDs are drawn from a pool
+// smaller than size so both lists contain repeats.
+func Day01(r *rand.Rand, size int) string {
+	pool := make([]int, max(size/2, 1))
+	for i := range pool {
+		pool[i] = 10000 + r.IntN(90000)
+	}
+
+	lines := make([]string, size)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("%d   %d", pool[r.IntN(len(pool))], pool[r.IntN(len(pool))])
+	}
+	return join(lines)
+}
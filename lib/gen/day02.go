@@ -0,0 +1,36 @@
+package gen
+
+import (
+	"math/rand/v2"
+	"strconv"
+	"strings"
+)
+
+// Day02 generates size reports of 5 to 8 levels. Most reports are a steady
+// climb or descent, with a random level occasionally corrupted so that some
+// are only safe with the problem dampener and some are unsafe.
+func Day02(r *rand.Rand, size int) string {
+	lines := make([]string, size)
+	for i := range lines {
+		levels := make([]int, 5+r.IntN(4))
+		levels[0] = 10 + r.IntN(80)
+		direction := 1
+		if r.IntN(2) == 0 {
+			direction = -1
+		}
+		for j := 1; j < len(levels); j++ {
+			levels[j] = levels[j-1] + direction*(1+r.IntN(3))
+		}
+
+		for range r.IntN(3) {
+			levels[r.IntN(len(levels))] += r.IntN(9) - 4
+		}
+
+		parts := make([]string, len(levels))
+		for j, level := range levels {
+			parts[j] = strconv.Itoa(level)
+		}
+		lines[i] = strings.Join(parts, " ")
+	}
+	return join(lines)
+}
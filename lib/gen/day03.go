@@ -0,0 +1,44 @@
+package gen
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+)
+
+var day03Noise = []string{
+	"mul(", "mul[", "mul(4*", "mul ( 2 , 4 )", "don't", "do(", ")", ",", "?", "!", "@", "^", "%", "&", "*", "+", "-", "<", ">", "[", "]", "{", "}", "'", "from()", "where()", "select()", "how()", "what()", "who()", "why()", " ",
+}
+
+// Day03 generates corrupted memory containing size instructions, surrounded by
+// noise and near-miss instructions, spread over a handful of lines.
+func Day03(r *rand.Rand, size int) string {
+	lineCount := 1 + size/100
+	lines := make([]strings.Builder, lineCount)
+
+	for range size {
+		b := &lines[r.IntN(lineCount)]
+
+		for range r.IntN(4) {
+			b.WriteString(day03Noise[r.IntN(len(day03Noise))])
+		}
+
+		switch n := r.IntN(10); {
+		case n == 0:
+			b.WriteString("do()")
+		case n == 1:
+			b.WriteString("don't()")
+		case n == 2:
+			// too many digits to be a valid instruction
+			fmt.Fprintf(b, "mul(%d,%d)", 1000+r.IntN(9000), r.IntN(1000))
+		default:
+			fmt.Fprintf(b, "mul(%d,%d)", r.IntN(1000), r.IntN(1000))
+		}
+	}
+
+	result := make([]string, lineCount)
+	for i := range lines {
+		result[i] = lines[i].String()
+	}
+	return join(result)
+}
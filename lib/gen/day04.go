@@ -0,0 +1,29 @@
+package gen
+
+import "math/rand/v2"
+
+// Day04 generates a size x size word search of the letters X, M, A and S, with
+// extra copies of XMAS written in random directions.
+func Day04(r *rand.Rand, size int) string {
+	const letters = "XMAS"
+	grid := NewGrid(size, size, '.')
+	for row := range grid {
+		for column := range grid[row] {
+			grid[row][column] = letters[r.IntN(len(letters))]
+		}
+	}
+
+	directions := [][2]int{{1, -1}, {1, 1}, {-1, 1}, {-1, -1}, {0, 1}, {1, 0}, {0, -1}, {-1, 0}}
+	for range size {
+		row, column := r.IntN(size), r.IntN(size)
+		d := directions[r.IntN(len(directions))]
+		if !grid.InBounds(row+3*d[0], column+3*d[1]) {
+			continue
+		}
+		for i := range len(letters) {
+			grid[row+i*d[0]][column+i*d[1]] = letters[i]
+		}
+	}
+
+	return grid.String()
+}
@@ -0,0 +1,48 @@
+package gen
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Day05 generates page ordering rules forming a total order over up to 89
+// two-digit pages, followed by size updates of an odd number of pages. Around
+// half of the updates are already correctly ordered.
+func Day05(r *rand.Rand, size int) string {
+	pages := r.Perm(90)[:min(89, max(5, size))]
+	for i := range pages {
+		pages[i] += 10
+	}
+
+	rules := make([]string, 0, len(pages)*(len(pages)-1)/2)
+	for i, before := range pages {
+		for _, after := range pages[i+1:] {
+			rules = append(rules, fmt.Sprintf("%d|%d", before, after))
+		}
+	}
+	r.Shuffle(len(rules), func(i, j int) { rules[i], rules[j] = rules[j], rules[i] })
+
+	updates := make([]string, size)
+	for i := range updates {
+		length := min(len(pages), 3+2*r.IntN(6))
+		if length%2 == 0 {
+			length -= 1
+		}
+
+		indexes := r.Perm(len(pages))[:length]
+		if r.IntN(2) == 0 {
+			slices.Sort(indexes)
+		}
+
+		update := make([]string, length)
+		for j, index := range indexes {
+			update[j] = strconv.Itoa(pages[index])
+		}
+		updates[i] = strings.Join(update, ",")
+	}
+
+	return join(rules) + "\n\n" + join(updates)
+}
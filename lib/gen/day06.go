@@ -0,0 +1,52 @@
+package gen
+
+import "math/rand/v2"
+
+// Day06 generates a size x size lab with scattered obstructions and a guard
+// facing north. Layouts where the guard would never leave the lab are
+// rejected, as they have no answer.
+func Day06(r *rand.Rand, size int) string {
+	size = max(size, 2)
+
+	for {
+		grid := NewGrid(size, size, '.')
+		for row := range grid {
+			for column := range grid[row] {
+				if r.IntN(10) == 0 {
+					grid[row][column] = '#'
+				}
+			}
+		}
+
+		row, column := r.IntN(size), r.IntN(size)
+		grid[row][column] = '^'
+
+		if day06Leaves(grid, row, column) {
+			return grid.String()
+		}
+	}
+}
+
+func day06Leaves(grid Grid, row, column int) bool {
+	direction := 0 // index into cardinals, starting north
+	visited := map[[3]int]struct{}{}
+
+	for {
+		state := [3]int{row, column, direction}
+		if _, ok := visited[state]; ok {
+			return false
+		}
+		visited[state] = struct{}{}
+
+		next := [2]int{row + cardinals[direction][0], column + cardinals[direction][1]}
+		if !grid.InBounds(next[0], next[1]) {
+			return true
+		}
+
+		if grid[next[0]][next[1]] == '#' {
+			direction = (direction + 1) % len(cardinals)
+		} else {
+			row, column = next[0], next[1]
+		}
+	}
+}
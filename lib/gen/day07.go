@@ -0,0 +1,43 @@
+package gen
+
+import (
+	"math/rand/v2"
+	"strconv"
+	"strings"
+)
+
+// Day07 generates size calibration equations of 2 to 7 numbers. Test values
+// are built from random operators (add, multiply, concatenate) so that many
+// equations are solvable, with a quarter nudged off to be unsolvable.
+func Day07(r *rand.Rand, size int) string {
+	lines := make([]string, size)
+	for i := range lines {
+		numbers := make([]string, 2+r.IntN(6))
+		var value int
+		for j := range numbers {
+			n := 1 + r.IntN(20)
+			numbers[j] = strconv.Itoa(n)
+
+			if j == 0 {
+				value = n
+				continue
+			}
+
+			switch r.IntN(3) {
+			case 0:
+				value += n
+			case 1:
+				value *= n
+			case 2:
+				value, _ = strconv.Atoi(strconv.Itoa(value) + numbers[j])
+			}
+		}
+
+		if r.IntN(4) == 0 {
+			value += 1 + r.IntN(10)
+		}
+
+		lines[i] = strconv.Itoa(value) + ": " + strings.Join(numbers, " ")
+	}
+	return join(lines)
+}
@@ -0,0 +1,22 @@
+package gen
+
+import "math/rand/v2"
+
+// Day08 generates a size x size map with roughly size antennas, spread over up
+// to 62 frequencies of 2 to 4 antennas each.
+func Day08(r *rand.Rand, size int) string {
+	const frequencies = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	size = max(size, 2)
+	grid := NewGrid(size, size, '.')
+
+	for _, frequency := range []byte(frequencies)[:min(len(frequencies), max(1, size/3))] {
+		for range 2 + r.IntN(3) {
+			row, column := r.IntN(size), r.IntN(size)
+			if grid[row][column] == '.' {
+				grid[row][column] = frequency
+			}
+		}
+	}
+
+	return grid.String()
+}
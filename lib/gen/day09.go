@@ -0,0 +1,18 @@
+package gen
+
+import "math/rand/v2"
+
+// Day09 generates a disk map of size digits (rounded up to an odd number so
+// the map ends with a file). Files are 1 to 9 blocks, free space 0 to 9.
+func Day09(r *rand.Rand, size int) string {
+	size = odd(size, 1)
+	diskMap := make([]byte, size)
+	for i := range diskMap {
+		if i%2 == 0 {
+			diskMap[i] = byte('1' + r.IntN(9))
+		} else {
+			diskMap[i] = byte('0' + r.IntN(10))
+		}
+	}
+	return string(diskMap)
+}
@@ -0,0 +1,30 @@
+package gen
+
+import "math/rand/v2"
+
+// Day10 generates a size x size topographic map of random heights, with around
+// size/2 hiking trails from 0 to 9 walked into it.
+func Day10(r *rand.Rand, size int) string {
+	size = max(size, 2)
+	grid := NewGrid(size, size, '0')
+	for row := range grid {
+		for column := range grid[row] {
+			grid[row][column] = byte('0' + r.IntN(10))
+		}
+	}
+
+	for range max(1, size/2) {
+		row, column := r.IntN(size), r.IntN(size)
+		grid[row][column] = '0'
+		for height := byte('1'); height <= '9'; height++ {
+			d := cardinals[r.IntN(len(cardinals))]
+			if !grid.InBounds(row+d[0], column+d[1]) {
+				break
+			}
+			row, column = row+d[0], column+d[1]
+			grid[row][column] = height
+		}
+	}
+
+	return grid.String()
+}
@@ -0,0 +1,19 @@
+package gen
+
+import (
+	"math/rand/v2"
+	"strconv"
+	"strings"
+
+	"github.com/max-nicholson/advent-of-code-2024/lib"
+)
+
+// Day11 generates a line of size stones, engraved with numbers up to 999999.
+func Day11(r *rand.Rand, size int) string {
+	stones := make([]string, size)
+	for i := range stones {
+		// bias towards small numbers, including 0
+		stones[i] = strconv.Itoa(r.IntN(lib.PowInt(10, 1+r.IntN(6))))
+	}
+	return strings.Join(stones, " ")
+}
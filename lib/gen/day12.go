@@ -0,0 +1,26 @@
+package gen
+
+import "math/rand/v2"
+
+// Day12 generates a size x size garden. Each plot usually copies the plant of
+// a neighbour, which grows irregular regions (including ones inside others).
+func Day12(r *rand.Rand, size int) string {
+	const plants = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	kinds := min(len(plants), 2+size/4)
+	grid := NewGrid(size, size, 'A')
+
+	for row := range grid {
+		for column := range grid[row] {
+			switch n := r.IntN(10); {
+			case n < 4 && row > 0:
+				grid[row][column] = grid[row-1][column]
+			case n < 8 && column > 0:
+				grid[row][column] = grid[row][column-1]
+			default:
+				grid[row][column] = plants[r.IntN(kinds)]
+			}
+		}
+	}
+
+	return grid.String()
+}
@@ -0,0 +1,35 @@
+package gen
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+)
+
+// Day13 generates size claw machines. Buttons are never parallel, and most
+// prizes are reachable with up to 100 presses of each button.
+func Day13(r *rand.Rand, size int) string {
+	machines := make([]string, size)
+	for i := range machines {
+		var ax, ay, bx, by int
+		for ax*by == ay*bx {
+			ax, ay = 10+r.IntN(90), 10+r.IntN(90)
+			bx, by = 10+r.IntN(90), 10+r.IntN(90)
+		}
+
+		var px, py int
+		if r.IntN(3) == 0 {
+			px, py = 1000+r.IntN(19000), 1000+r.IntN(19000)
+		} else {
+			a, b := r.IntN(101), r.IntN(101)
+			px, py = a*ax+b*bx, a*ay+b*by
+		}
+
+		machines[i] = strings.Join([]string{
+			fmt.Sprintf("Button A: X+%d, Y+%d", ax, ay),
+			fmt.Sprintf("Button B: X+%d, Y+%d", bx, by),
+			fmt.Sprintf("Prize: X=%d, Y=%d", px, py),
+		}, "\n")
+	}
+	return strings.Join(machines, "\n\n")
+}
@@ -0,0 +1,37 @@
+package gen
+
+import (
+	"fmt"
+	"math/rand/v2"
+)
+
+// Day14Width and Day14Height are the dimensions of the real puzzle's bathroom.
+const (
+	Day14Width  = 101
+	Day14Height = 103
+)
+
+// Day14 generates size robots (at most one per tile) in a Day14Width x
+// Day14Height bathroom. The robots are planted so that at some random time no
+// two robots overlap, as they do when forming the Easter egg picture.
+func Day14(r *rand.Rand, size int) string {
+	size = min(size, Day14Width*Day14Height)
+	t := r.IntN(Day14Width * Day14Height)
+
+	lines := make([]string, size)
+	for i, tile := range r.Perm(Day14Width * Day14Height)[:size] {
+		x, y := tile%Day14Width, tile/Day14Width
+
+		var vx, vy int
+		for vx == 0 && vy == 0 {
+			vx, vy = r.IntN(199)-99, r.IntN(199)-99
+		}
+
+		// rewind from the planted position at time t
+		px := ((x-vx*t)%Day14Width + Day14Width) % Day14Width
+		py := ((y-vy*t)%Day14Height + Day14Height) % Day14Height
+
+		lines[i] = fmt.Sprintf("p=%d,%d v=%d,%d", px, py, vx, vy)
+	}
+	return join(lines)
+}
@@ -0,0 +1,40 @@
+package gen
+
+import (
+	"math/rand/v2"
+	"strings"
+)
+
+// Day15 generates a size x size walled warehouse with boxes, a few internal
+// walls and a robot, followed by size*10 moves over lines of 70 characters.
+func Day15(r *rand.Rand, size int) string {
+	size = max(size, 3)
+	grid := NewGrid(size, size, '.')
+	for row := range grid {
+		for column := range grid[row] {
+			if row == 0 || column == 0 || row == size-1 || column == size-1 {
+				grid[row][column] = '#'
+				continue
+			}
+
+			switch n := r.IntN(20); {
+			case n < 1:
+				grid[row][column] = '#'
+			case n < 6:
+				grid[row][column] = 'O'
+			}
+		}
+	}
+	grid[1+r.IntN(size-2)][1+r.IntN(size-2)] = '@'
+
+	const moves = "<>^v"
+	var b strings.Builder
+	for i := range size * 10 {
+		if i > 0 && i%70 == 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteByte(moves[r.IntN(len(moves))])
+	}
+
+	return grid.String() + "\n\n" + b.String()
+}
@@ -0,0 +1,26 @@
+package gen
+
+import "math/rand/v2"
+
+// Day16 generates a size x size maze (rounded up to odd) with the start in the
+// bottom-left and the end in the top-right. Around one in ten internal walls
+// is knocked through, so there are usually several routes to compare.
+func Day16(r *rand.Rand, size int) string {
+	size = odd(size, 5)
+	grid := Maze(r, size, size)
+
+	for row := 1; row < size-1; row++ {
+		for column := 1; column < size-1; column++ {
+			// walls between two open cells in a line
+			between := (row%2 == 1) != (column%2 == 1)
+			if between && grid[row][column] == '#' && r.IntN(10) == 0 {
+				grid[row][column] = '.'
+			}
+		}
+	}
+
+	grid[size-2][1] = 'S'
+	grid[1][size-2] = 'E'
+
+	return grid.String()
+}
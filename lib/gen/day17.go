@@ -0,0 +1,48 @@
+package gen
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+)
+
+// Day17 generates a 3-bit computer program shaped like the real puzzle input:
+// a single loop which consumes A three bits at a time, scrambling them through
+// B and C before outputting a value. Register A is seeded with size octal
+// digits (at most 16), which is how many values the program will output.
+func Day17(r *rand.Rand, size int) string {
+	size = min(size, 16)
+
+	// B and C must be derived from A before they're combined, so that each
+	// iteration of the loop only depends on A
+	body := [][2]int{
+		{2, 4},         // bst A
+		{1, r.IntN(8)}, // bxl
+		{7, 5},         // cdv B
+	}
+	tail := [][2]int{
+		{1, r.IntN(8)}, // bxl
+		{4, r.IntN(8)}, // bxc (operand ignored)
+	}
+	r.Shuffle(len(tail), func(i, j int) { tail[i], tail[j] = tail[j], tail[i] })
+	body = append(body, tail...)
+	body = append(body, [2]int{5, 5}) // out B
+
+	// adv can happen anywhere after C is derived from A
+	advAt := 3 + r.IntN(len(body)-2)
+	body = append(body[:advAt], append([][2]int{{0, 3}}, body[advAt:]...)...)
+	body = append(body, [2]int{3, 0}) // jnz 0
+
+	program := make([]string, 0, len(body)*2)
+	for _, instruction := range body {
+		program = append(program, strconv.Itoa(instruction[0]), strconv.Itoa(instruction[1]))
+	}
+
+	a := 1 + r.IntN(7)
+	for range size - 1 {
+		a = a<<3 | r.IntN(8)
+	}
+
+	return fmt.Sprintf("Register A: %d\nRegister B: 0\nRegister C: 0\n\nProgram: %s", a, strings.Join(program, ","))
+}
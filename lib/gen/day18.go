@@ -0,0 +1,24 @@
+package gen
+
+import (
+	"fmt"
+	"math/rand/v2"
+)
+
+// Day18 generates the falling bytes for a memory space of size x size
+// (coordinates 0 to size-1). Every coordinate other than the start and exit
+// falls exactly once, in a random order, so the exit is always cut off
+// eventually.
+func Day18(r *rand.Rand, size int) string {
+	size = max(size, 2)
+
+	lines := make([]string, 0, size*size-2)
+	for _, i := range r.Perm(size * size) {
+		x, y := i%size, i/size
+		if (x == 0 && y == 0) || (x == size-1 && y == size-1) {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%d,%d", x, y))
+	}
+	return join(lines)
+}
@@ -0,0 +1,47 @@
+package gen
+
+import (
+	"math/rand/v2"
+	"strings"
+)
+
+// Day19 generates size towel patterns and size designs. Half of the designs
+// are made by joining towels together; the rest are random stripes which may
+// not be possible.
+func Day19(r *rand.Rand, size int) string {
+	const colours = "wubrg"
+	stripes := func(length int) string {
+		b := make([]byte, length)
+		for i := range b {
+			b[i] = colours[r.IntN(len(colours))]
+		}
+		return string(b)
+	}
+
+	seen := map[string]struct{}{}
+	towels := make([]string, 0, size)
+	for range size {
+		towel := stripes(1 + r.IntN(8))
+		if _, ok := seen[towel]; ok {
+			continue
+		}
+		seen[towel] = struct{}{}
+		towels = append(towels, towel)
+	}
+
+	designs := make([]string, size)
+	for i := range designs {
+		if r.IntN(2) == 0 {
+			designs[i] = stripes(20 + r.IntN(40))
+			continue
+		}
+
+		var b strings.Builder
+		for b.Len() < 20 {
+			b.WriteString(towels[r.IntN(len(towels))])
+		}
+		designs[i] = b.String()
+	}
+
+	return strings.Join(towels, ", ") + "\n\n" + join(designs)
+}
@@ -0,0 +1,50 @@
+package gen
+
+import "math/rand/v2"
+
+// Day20 generates a size x size racetrack (rounded up to odd) with a single
+// track from start to end. The track is the path between two far apart cells
+// of a random maze, with every other cell walled off.
+func Day20(r *rand.Rand, size int) string {
+	size = odd(size, 5)
+	maze := Maze(r, size, size)
+
+	type cell struct{ row, column int }
+
+	// Breadth-first search over the maze, returning the furthest cell from
+	// start and the route taken to reach every cell
+	explore := func(start cell) (cell, map[cell]cell) {
+		previous := map[cell]cell{start: start}
+		queue := []cell{start}
+		var last cell
+		for len(queue) > 0 {
+			last = queue[0]
+			queue = queue[1:]
+			for _, d := range cardinals {
+				next := cell{last.row + d[0], last.column + d[1]}
+				if maze[next.row][next.column] == '#' {
+					continue
+				}
+				if _, ok := previous[next]; ok {
+					continue
+				}
+				previous[next] = last
+				queue = append(queue, next)
+			}
+		}
+		return last, previous
+	}
+
+	open := cell{1 + 2*r.IntN(size/2), 1 + 2*r.IntN(size/2)}
+	start, _ := explore(open)
+	end, previous := explore(start)
+
+	grid := NewGrid(size, size, '#')
+	for current := end; current != start; current = previous[current] {
+		grid[current.row][current.column] = '.'
+	}
+	grid[start.row][start.column] = 'S'
+	grid[end.row][end.column] = 'E'
+
+	return grid.String()
+}
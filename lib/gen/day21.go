@@ -0,0 +1,15 @@
+package gen
+
+import (
+	"fmt"
+	"math/rand/v2"
+)
+
+// Day21 generates size door codes of three digits followed by A.
+func Day21(r *rand.Rand, size int) string {
+	codes := make([]string, size)
+	for i := range codes {
+		codes[i] = fmt.Sprintf("%03dA", r.IntN(1000))
+	}
+	return join(codes)
+}
@@ -0,0 +1,15 @@
+package gen
+
+import (
+	"math/rand/v2"
+	"strconv"
+)
+
+// Day22 generates size initial secret numbers for the buyers.
+func Day22(r *rand.Rand, size int) string {
+	secrets := make([]string, size)
+	for i := range secrets {
+		secrets[i] = strconv.Itoa(1 + r.IntN(16777215))
+	}
+	return join(secrets)
+}
@@ -0,0 +1,63 @@
+package gen
+
+import (
+	"math/rand/v2"
+	"slices"
+	"strings"
+)
+
+// Day23 generates a network of size computers (at most 676) with two-letter
+// names. See Day23Party.
+func Day23(r *rand.Rand, size int) string {
+	network, _ := Day23Party(r, size)
+	return network
+}
+
+// Day23Party generates a network of size computers, where each computer has a
+// handful of random connections. A clique larger than any likely to occur by
+// chance is planted among them, and its names are returned as the expected
+// LAN party.
+func Day23Party(r *rand.Rand, size int) (string, []string) {
+	size = min(max(size, 4), 26*26)
+
+	names := make([]string, size)
+	for i, n := range r.Perm(26 * 26)[:size] {
+		names[i] = string([]byte{byte('a' + n/26), byte('a' + n%26)})
+	}
+
+	edges := map[[2]int]struct{}{}
+	connect := func(a, b int) {
+		if a == b {
+			return
+		}
+		if a > b {
+			a, b = b, a
+		}
+		edges[[2]int{a, b}] = struct{}{}
+	}
+
+	for a := range size {
+		for range 2 {
+			connect(a, r.IntN(size))
+		}
+	}
+
+	clique := r.Perm(size)[:min(size, 5+size/100)]
+	party := make([]string, len(clique))
+	for i, a := range clique {
+		party[i] = names[a]
+		for _, b := range clique[i+1:] {
+			connect(a, b)
+		}
+	}
+
+	lines := make([]string, 0, len(edges))
+	for edge := range edges {
+		lines = append(lines, names[edge[0]]+"-"+names[edge[1]])
+	}
+	// map iteration order isn't seeded
+	slices.Sort(lines)
+	r.Shuffle(len(lines), func(i, j int) { lines[i], lines[j] = lines[j], lines[i] })
+
+	return strings.Join(lines, "\n"), party
+}
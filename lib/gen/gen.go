@@ -0,0 +1,149 @@
+// Package gen produces random, valid puzzle inputs for stress testing solutions.
+package gen
+
+import (
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"strings"
+)
+
+// A Generator produces a puzzle input from r, with size controlling how large
+// the input is (number of lines, grid width, ...). The meaning of size is
+// documented on each day's generator.
+type Generator func(r *rand.Rand, size int) string
+
+var generators = map[int]Generator{
+	1:  Day01,
+	2:  Day02,
+	3:  Day03,
+	4:  Day04,
+	5:  Day05,
+	6:  Day06,
+	7:  Day07,
+	8:  Day08,
+	9:  Day09,
+	10: Day10,
+	11: Day11,
+	12: Day12,
+	13: Day13,
+	14: Day14,
+	15: Day15,
+	16: Day16,
+	17: Day17,
+	18: Day18,
+	19: Day19,
+	20: Day20,
+	21: Day21,
+	22: Day22,
+	23: Day23,
+}
+
+// Days returns every day with a generator, in ascending order.
+func Days() []int {
+	return slices.Sorted(maps.Keys(generators))
+}
+
+// New returns a deterministic random source for seed.
+func New(seed uint64) *rand.Rand {
+	return rand.New(rand.NewPCG(seed, seed))
+}
+
+// Generate produces an input for day, which is identical for the same size and seed.
+func Generate(day int, size int, seed uint64) (string, error) {
+	generator, ok := generators[day]
+	if !ok {
+		return "", fmt.Errorf("no generator for day %d", day)
+	}
+
+	if size < 1 {
+		return "", fmt.Errorf("size must be positive, got %d", size)
+	}
+
+	return generator(New(seed), size), nil
+}
+
+// Grid is a mutable character grid, indexed [row][column].
+type Grid [][]byte
+
+func NewGrid(rows, columns int, fill byte) Grid {
+	grid := make(Grid, rows)
+	for r := range grid {
+		grid[r] = make([]byte, columns)
+		for c := range grid[r] {
+			grid[r][c] = fill
+		}
+	}
+	return grid
+}
+
+func (g Grid) InBounds(row, column int) bool {
+	return row >= 0 && row < len(g) && column >= 0 && column < len(g[0])
+}
+
+func (g Grid) String() string {
+	var b strings.Builder
+	b.Grow(len(g) * (len(g[0]) + 1))
+	for i, row := range g {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		b.Write(row)
+	}
+	return b.String()
+}
+
+var cardinals = [][2]int{{-1, 0}, {0, 1}, {1, 0}, {0, -1}}
+
+// Maze carves a perfect maze (exactly one path between any two open cells) into
+// a grid of walls using a randomised depth-first search. Open cells sit on odd
+// rows and columns, so rows and columns should be odd.
+func Maze(r *rand.Rand, rows, columns int) Grid {
+	grid := NewGrid(rows, columns, '#')
+
+	type cell struct{ row, column int }
+	start := cell{1, 1}
+	grid[start.row][start.column] = '.'
+	stack := []cell{start}
+
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+
+		var options []cell
+		for _, d := range cardinals {
+			next := cell{current.row + 2*d[0], current.column + 2*d[1]}
+			if next.row <= 0 || next.row >= rows-1 || next.column <= 0 || next.column >= columns-1 {
+				continue
+			}
+			if grid[next.row][next.column] == '#' {
+				options = append(options, next)
+			}
+		}
+
+		if len(options) == 0 {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		next := options[r.IntN(len(options))]
+		grid[(current.row+next.row)/2][(current.column+next.column)/2] = '.'
+		grid[next.row][next.column] = '.'
+		stack = append(stack, next)
+	}
+
+	return grid
+}
+
+// odd rounds n up to the nearest odd number, and to at least minimum.
+func odd(n int, minimum int) int {
+	n = max(n, minimum)
+	if n%2 == 0 {
+		n += 1
+	}
+	return n
+}
+
+func join(lines []string) string {
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,81 @@
+package gen_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/max-nicholson/advent-of-code-2024/lib/gen"
+)
+
+func TestGenerateIsDeterministic(t *testing.T) {
+	for _, day := range gen.Days() {
+		a, err := gen.Generate(day, 20, 42)
+		if err != nil {
+			t.Fatalf("day %d: %v", day, err)
+		}
+		b, err := gen.Generate(day, 20, 42)
+		if err != nil {
+			t.Fatalf("day %d: %v", day, err)
+		}
+		if a != b {
+			t.Errorf("day %d: want same input for the same seed", day)
+		}
+		if a == "" {
+			t.Errorf("day %d: want non-empty input", day)
+		}
+	}
+}
+
+func TestGenerateUnknownDay(t *testing.T) {
+	if _, err := gen.Generate(26, 10, 1); err == nil {
+		t.Fatalf("want error for day without a generator")
+	}
+}
+
+func TestDay20SingleTrack(t *testing.T) {
+	for seed := range uint64(20) {
+		grid := strings.Split(gen.Day20(gen.New(seed), 15), "\n")
+
+		for r, row := range grid {
+			for c, cell := range row {
+				if cell == '#' {
+					continue
+				}
+
+				neighbours := 0
+				for _, d := range [][2]int{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+					if grid[r+d[0]][c+d[1]] != '#' {
+						neighbours += 1
+					}
+				}
+
+				want := 2
+				if cell == 'S' || cell == 'E' {
+					want = 1
+				}
+				if neighbours != want {
+					t.Fatalf("seed %d: want %c at %d,%d to have %d track neighbours, got %d\n%s", seed, cell, r, c, want, neighbours, strings.Join(grid, "\n"))
+				}
+			}
+		}
+	}
+}
+
+func TestDay23PartyIsConnected(t *testing.T) {
+	network, party := gen.Day23Party(gen.New(1), 200)
+
+	connections := map[string]struct{}{}
+	for _, line := range strings.Split(network, "\n") {
+		connections[line] = struct{}{}
+	}
+
+	for i, a := range party {
+		for _, b := range party[i+1:] {
+			_, ab := connections[a+"-"+b]
+			_, ba := connections[b+"-"+a]
+			if !ab && !ba {
+				t.Fatalf("want %s and %s to be connected", a, b)
+			}
+		}
+	}
+}
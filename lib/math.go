@@ -48,3 +48,11 @@ func PowInt(base, exp int) int {
 
 	return result
 }
+
+// GCD is the greatest common divisor of a and b, which is never negative
+func GCD[T constraints.Integer](a, b T) T {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return Abs(a)
+}
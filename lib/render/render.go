@@ -0,0 +1,371 @@
+// Package render draws grid simulations as ANSI coloured terminal output, PNG
+// images and animated GIFs.
+package render
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// A Frame is a snapshot of a grid, where each cell is drawn as a rune
+type Frame interface {
+	Size() (width, height int)
+	At(x, y int) rune
+}
+
+// Runes is a Frame indexed [y][x]
+type Runes [][]rune
+
+func (r Runes) Size() (int, int) {
+	if len(r) == 0 {
+		return 0, 0
+	}
+	return len(r[0]), len(r)
+}
+
+func (r Runes) At(x, y int) rune {
+	return r[y][x]
+}
+
+// Blank returns a width x height frame where every cell is fill
+func Blank(width, height int, fill rune) Runes {
+	frame := make(Runes, height)
+	for y := range frame {
+		frame[y] = make([]rune, width)
+		for x := range frame[y] {
+			frame[y][x] = fill
+		}
+	}
+	return frame
+}
+
+// Lines is a Frame of ASCII lines, as read by lib.ReadLines
+type Lines []string
+
+func (l Lines) Size() (int, int) {
+	if len(l) == 0 {
+		return 0, 0
+	}
+	return len(l[0]), len(l)
+}
+
+func (l Lines) At(x, y int) rune {
+	return rune(l[y][x])
+}
+
+// A Palette chooses the colour each cell is drawn in
+type Palette struct {
+	Colours map[rune]color.RGBA
+	// Default is used for any rune not in Colours
+	Default color.RGBA
+}
+
+func (p Palette) Colour(cell rune) color.RGBA {
+	if c, ok := p.Colours[cell]; ok {
+		return c
+	}
+	return p.Default
+}
+
+// paletted returns every colour in the palette, with Default first and the
+// rest sorted so the same palette always encodes the same way. Paletted images
+// index colours with a byte, so there can be at most 256
+func (p Palette) paletted() (color.Palette, error) {
+	var others []color.RGBA
+	for _, c := range p.Colours {
+		if c != p.Default && !slices.Contains(others, c) {
+			others = append(others, c)
+		}
+	}
+	slices.SortFunc(others, func(a, b color.RGBA) int {
+		return cmp.Compare(packed(a), packed(b))
+	})
+
+	if len(others) >= 256 {
+		return nil, fmt.Errorf("palette has %d colours, want at most 256", len(others)+1)
+	}
+
+	colours := color.Palette{p.Default}
+	for _, c := range others {
+		colours = append(colours, c)
+	}
+	return colours, nil
+}
+
+func packed(c color.RGBA) uint32 {
+	return uint32(c.R)<<24 | uint32(c.G)<<16 | uint32(c.B)<<8 | uint32(c.A)
+}
+
+// ANSI writes the frame as text, with each cell coloured using 24-bit colour
+// escape codes
+func ANSI(w io.Writer, frame Frame, palette Palette) error {
+	b := bufio.NewWriter(w)
+	width, height := frame.Size()
+
+	for y := range height {
+		for x := range width {
+			cell := frame.At(x, y)
+			c := palette.Colour(cell)
+			fmt.Fprintf(b, "\x1b[38;2;%d;%d;%dm%c", c.R, c.G, c.B, cell)
+		}
+		b.WriteString("\x1b[0m\n")
+	}
+
+	return b.Flush()
+}
+
+// Image draws each cell of the frame as a scale x scale square
+func Image(frame Frame, palette Palette, scale int) (*image.Paletted, error) {
+	width, height := frame.Size()
+	colours, err := palette.paletted()
+	if err != nil {
+		return nil, err
+	}
+	img := image.NewPaletted(image.Rect(0, 0, width*scale, height*scale), colours)
+
+	for y := range height {
+		for x := range width {
+			index := uint8(colours.Index(palette.Colour(frame.At(x, y))))
+			for dy := range scale {
+				for dx := range scale {
+					img.SetColorIndex(x*scale+dx, y*scale+dy, index)
+				}
+			}
+		}
+	}
+
+	return img, nil
+}
+
+func PNG(w io.Writer, frame Frame, palette Palette, scale int) error {
+	img, err := Image(frame, palette, scale)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
+
+// A Sink receives frames as a simulation progresses
+type Sink interface {
+	Frame(frame Frame) error
+}
+
+type discard struct{}
+
+func (discard) Frame(Frame) error { return nil }
+func (discard) Close() error      { return nil }
+func (discard) Discarding() bool  { return true }
+
+// Discard is a Sink which ignores every frame, for running simulations without
+// rendering them
+var Discard Recorder = discard{}
+
+// Discarding is whether sink throws frames away, so there's no point drawing
+// them. Sinks say so with a Discarding() bool method, as Discard does
+func Discarding(sink Sink) bool {
+	d, ok := sink.(interface{ Discarding() bool })
+	return ok && d.Discarding()
+}
+
+// A Recorder is a Sink which must be closed once the simulation is finished
+type Recorder interface {
+	Sink
+	io.Closer
+}
+
+// Terminal draws each frame over the last one, pausing for Delay between them
+type Terminal struct {
+	W       io.Writer
+	Palette Palette
+	Delay   time.Duration
+}
+
+func (t Terminal) Frame(frame Frame) error {
+	// move to the top left and clear the screen
+	if _, err := io.WriteString(t.W, "\x1b[H\x1b[2J"); err != nil {
+		return err
+	}
+	if err := ANSI(t.W, frame, t.Palette); err != nil {
+		return err
+	}
+	time.Sleep(t.Delay)
+	return nil
+}
+
+func (t Terminal) Close() error { return nil }
+
+// GIF collects frames into an animation
+type GIF struct {
+	Palette Palette
+	// Scale is the size in pixels of each cell
+	Scale int
+	// Delay between frames, in 100ths of a second
+	Delay int
+	// MaxFrames caps how many frames are held in memory, or 0 for no cap.
+	// When it's reached, every other frame is dropped and from then on only
+	// every other frame is kept, so long simulations are sampled evenly
+	MaxFrames int
+
+	images []*image.Paletted
+	// stride is the gap between kept frames
+	stride int
+	seen   int
+	// last is a copy of the latest frame when it wasn't kept, so the
+	// animation still ends where the simulation did. It's only drawn if it's
+	// still the last one when encoding
+	last Runes
+	// skipped is whether last is still waiting to be drawn
+	skipped bool
+}
+
+func (g *GIF) Frame(frame Frame) error {
+	if g.stride == 0 {
+		g.stride = 1
+	}
+
+	index := g.seen
+	g.seen++
+	g.skipped = false
+
+	if index%g.stride == 0 && g.MaxFrames > 0 && len(g.images) >= g.MaxFrames {
+		kept := g.images[:0]
+		for i := 0; i < len(g.images); i += 2 {
+			kept = append(kept, g.images[i])
+		}
+		clear(g.images[len(kept):])
+		g.images = kept
+		g.stride *= 2
+	}
+
+	if index%g.stride != 0 {
+		g.last = copyFrame(g.last, frame)
+		g.skipped = true
+		return nil
+	}
+
+	img, err := Image(frame, g.Palette, g.Scale)
+	if err != nil {
+		return err
+	}
+	g.images = append(g.images, img)
+	return nil
+}
+
+// copyFrame copies frame into into, reusing its rows when it's the same size
+func copyFrame(into Runes, frame Frame) Runes {
+	width, height := frame.Size()
+	if w, h := into.Size(); w != width || h != height {
+		into = Blank(width, height, 0)
+	}
+	for y := range height {
+		for x := range width {
+			into[y][x] = frame.At(x, y)
+		}
+	}
+	return into
+}
+
+// Frames is the number of frames the animation has so far
+func (g *GIF) Frames() int {
+	if g.skipped {
+		return len(g.images) + 1
+	}
+	return len(g.images)
+}
+
+func (g *GIF) Encode(w io.Writer) error {
+	var animation gif.GIF
+	for _, img := range g.images {
+		animation.Image = append(animation.Image, img)
+		animation.Delay = append(animation.Delay, g.Delay)
+	}
+	if g.skipped {
+		img, err := Image(g.last, g.Palette, g.Scale)
+		if err != nil {
+			return err
+		}
+		animation.Image = append(animation.Image, img)
+		animation.Delay = append(animation.Delay, g.Delay)
+	}
+	return gif.EncodeAll(w, &animation)
+}
+
+type fileRecorder struct {
+	path   string
+	encode func(w io.Writer) error
+	Sink
+}
+
+func (f fileRecorder) Close() error {
+	file, err := os.Create(f.path)
+	if err != nil {
+		return err
+	}
+
+	if err := f.encode(file); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to encode %s: %w", f.path, err)
+	}
+
+	return file.Close()
+}
+
+type last struct {
+	frame Frame
+}
+
+func (l *last) Frame(frame Frame) error {
+	l.frame = frame
+	return nil
+}
+
+// maxFrames keeps GIFs Open writes to around 100MB in memory for a 100x100
+// grid, however long the simulation runs
+const maxFrames = 600
+
+// Open returns a Recorder chosen by path:
+//   - "" discards every frame
+//   - "-" animates the frames in the terminal
+//   - *.gif writes up to maxFrames, sampled evenly, as an animation when closed
+//   - *.png writes the last frame as an image when closed
+func Open(path string, palette Palette) (Recorder, error) {
+	switch path {
+	case "":
+		return Discard, nil
+	case "-":
+		return Terminal{W: os.Stdout, Palette: palette, Delay: 50 * time.Millisecond}, nil
+	}
+
+	ext := filepath.Ext(path)
+	if ext != ".gif" && ext != ".png" {
+		return nil, fmt.Errorf("unable to render to %s; want -, *.gif or *.png", path)
+	}
+
+	// fail now rather than after the whole simulation has run
+	if _, err := palette.paletted(); err != nil {
+		return nil, err
+	}
+
+	if ext == ".gif" {
+		g := &GIF{Palette: palette, Scale: 4, Delay: 5, MaxFrames: maxFrames}
+		return fileRecorder{path: path, encode: g.Encode, Sink: g}, nil
+	}
+
+	l := &last{}
+	return fileRecorder{path: path, Sink: l, encode: func(w io.Writer) error {
+		if l.frame == nil {
+			return fmt.Errorf("no frames rendered")
+		}
+		return PNG(w, l.frame, palette, 4)
+	}}, nil
+}
@@ -0,0 +1,203 @@
+package render_test
+
+import (
+	"bytes"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/max-nicholson/advent-of-code-2024/lib/render"
+)
+
+var palette = render.Palette{
+	Colours: map[rune]color.RGBA{
+		'#': {255, 0, 0, 255},
+	},
+	Default: color.RGBA{0, 0, 0, 255},
+}
+
+func TestANSI(t *testing.T) {
+	var b strings.Builder
+	if err := render.ANSI(&b, render.Lines{"#.", ".#"}, palette); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "\x1b[38;2;255;0;0m#\x1b[38;2;0;0;0m.\x1b[0m\n\x1b[38;2;0;0;0m.\x1b[38;2;255;0;0m#\x1b[0m\n"
+	if got := b.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestPNG(t *testing.T) {
+	var b bytes.Buffer
+	if err := render.PNG(&b, render.Lines{"#..", "..#"}, palette, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := png.Decode(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size := img.Bounds().Size(); size.X != 6 || size.Y != 4 {
+		t.Fatalf("got %v, want 6x4", size)
+	}
+
+	if r, _, _, _ := img.At(5, 3).RGBA(); r>>8 != 255 {
+		t.Errorf("want bottom right to be red, got %v", img.At(5, 3))
+	}
+	if r, _, _, _ := img.At(2, 0).RGBA(); r != 0 {
+		t.Errorf("want top middle to be black, got %v", img.At(2, 0))
+	}
+}
+
+func TestGIF(t *testing.T) {
+	animation := &render.GIF{Palette: palette, Scale: 1, Delay: 10}
+	frame := render.Blank(3, 3, '.')
+	for i := range 3 {
+		frame[i][i] = '#'
+		if err := animation.Frame(frame); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var b bytes.Buffer
+	if err := animation.Encode(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := gif.DecodeAll(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(decoded.Image) != 3 {
+		t.Errorf("got %d frames, want 3", len(decoded.Image))
+	}
+}
+
+func TestOpenUnknownExtension(t *testing.T) {
+	if _, err := render.Open("frames.bmp", palette); err == nil {
+		t.Error("want an error for an unsupported extension")
+	}
+}
+
+func TestGIFMaxFrames(t *testing.T) {
+	animation := &render.GIF{Palette: palette, Scale: 1, Delay: 10, MaxFrames: 4}
+	// the same frame is drawn on each time, so skipped frames must be copied
+	frame := render.Blank(10, 1, '.')
+	for i := range 10 {
+		if i > 0 {
+			frame[0][i-1] = '.'
+		}
+		frame[0][i] = '#'
+		if err := animation.Frame(frame); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var b bytes.Buffer
+	frame[0][9] = '.'
+	if err := animation.Encode(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := gif.DecodeAll(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// sampled every 4th frame, followed by the last
+	want := []int{0, 4, 8, 9}
+	var got []int
+	for _, img := range decoded.Image {
+		for x := range 10 {
+			if r, _, _, _ := img.At(x, 0).RGBA(); r != 0 {
+				got = append(got, x)
+			}
+		}
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got frames %v, want %v", got, want)
+	}
+}
+
+func TestPaletteOrder(t *testing.T) {
+	colours := render.Palette{
+		Colours: map[rune]color.RGBA{
+			'a': {0, 0, 255, 255},
+			'b': {0, 255, 0, 255},
+			'c': {255, 0, 0, 255},
+			'd': {0, 255, 0, 255},
+			'e': {0, 0, 0, 255},
+		},
+		Default: color.RGBA{0, 0, 0, 255},
+	}
+
+	want := color.Palette{
+		color.RGBA{0, 0, 0, 255},
+		color.RGBA{0, 0, 255, 255},
+		color.RGBA{0, 255, 0, 255},
+		color.RGBA{255, 0, 0, 255},
+	}
+	for range 10 {
+		img, err := render.Image(render.Lines{"abcde"}, colours, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(img.Palette, want) {
+			t.Fatalf("got palette %v, want %v", img.Palette, want)
+		}
+	}
+}
+
+type muted struct{ render.Sink }
+
+func (muted) Discarding() bool { return true }
+
+func TestDiscarding(t *testing.T) {
+	cases := []struct {
+		name string
+		sink render.Sink
+		want bool
+	}{
+		{"discard", render.Discard, true},
+		{"own no-op sink", muted{}, true},
+		{"gif", &render.GIF{Palette: palette}, false},
+		{"wrapped gif", struct{ render.Sink }{&render.GIF{Palette: palette}}, false},
+	}
+
+	for _, c := range cases {
+		if got := render.Discarding(c.sink); got != c.want {
+			t.Errorf("Discarding(%s) = %t, want %t", c.name, got, c.want)
+		}
+	}
+}
+
+func TestPaletteTooLarge(t *testing.T) {
+	colours := render.Palette{Colours: map[rune]color.RGBA{}}
+	for i := range 256 {
+		colours.Colours[rune(i)] = color.RGBA{uint8(i), 1, 0, 255}
+	}
+
+	if _, err := render.Image(render.Lines{"ab"}, colours, 1); err == nil {
+		t.Error("Image() want error for 257 colours")
+	}
+	if err := (&render.GIF{Palette: colours, Scale: 1}).Frame(render.Lines{"ab"}); err == nil {
+		t.Error("Frame() want error for 257 colours")
+	}
+	for _, path := range []string{"frames.gif", "frame.png"} {
+		if _, err := render.Open(path, colours); err == nil {
+			t.Errorf("Open(%s) want error for 257 colours", path)
+		}
+	}
+
+	// with the default among them, 256 colours fit
+	colours.Default = color.RGBA{0, 1, 0, 255}
+	if _, err := render.Image(render.Lines{"ab"}, colours, 1); err != nil {
+		t.Errorf("Image() want 256 colours to fit, got %v", err)
+	}
+}
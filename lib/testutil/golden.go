@@ -0,0 +1,145 @@
+// Package testutil runs puzzle solutions against example inputs stored in a
+// package's testdata directory.
+//
+// Each example is a pair of files: NAME.in holds the puzzle input, and
+// NAME.want holds the expected answer for each part in the same format as the
+// output of `just run`, e.g.
+//
+//	part1: 11
+//	part2: 31
+//
+// Only the parts listed in the .want file are checked, as examples are often
+// only given for one of the parts. Running the tests with -update rewrites the
+// .want files with the current answers (of every registered part, if the
+// .want file doesn't exist yet).
+package testutil
+
+import (
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata/*.want files with the current answers")
+
+// A Part solves one part of the puzzle for the given input
+type Part func(input string) (string, error)
+
+// Lines adapts a part which takes the input as lines
+func Lines[T any](part func(lines []string) (T, error)) Part {
+	return func(input string) (string, error) {
+		answer, err := part(strings.Split(input, "\n"))
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprint(answer), nil
+	}
+}
+
+// Content adapts a part which takes the whole input
+func Content[T any](part func(content string) (T, error)) Part {
+	return func(input string) (string, error) {
+		answer, err := part(input)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprint(answer), nil
+	}
+}
+
+// Golden runs parts, keyed by name (e.g. "part1"), against every example in
+// testdata, comparing the answers with the matching .want file
+func Golden(t *testing.T, parts map[string]Part) {
+	t.Helper()
+
+	inputs, err := filepath.Glob(filepath.Join("testdata", "*.in"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Skip("no examples in testdata")
+	}
+
+	for _, path := range inputs {
+		name := strings.TrimSuffix(filepath.Base(path), ".in")
+
+		t.Run(name, func(t *testing.T) {
+			b, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			input := strings.TrimRight(string(b), "\n")
+
+			wantPath := strings.TrimSuffix(path, ".in") + ".want"
+			want, err := readWant(wantPath)
+			if err != nil && !(os.IsNotExist(err) && *update) {
+				t.Fatal(err)
+			}
+
+			names := slices.Sorted(maps.Keys(want))
+			if len(names) == 0 {
+				names = slices.Sorted(maps.Keys(parts))
+			}
+
+			got := make(map[string]string, len(names))
+			for _, name := range names {
+				part, ok := parts[name]
+				if !ok {
+					t.Fatalf("%s: no part registered for %s", wantPath, name)
+				}
+
+				answer, err := part(input)
+				if err != nil {
+					t.Errorf("%s: %v", name, err)
+					continue
+				}
+				got[name] = answer
+			}
+
+			if *update {
+				if err := writeWant(wantPath, got); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("%s mismatch (-want +got):\n%s", wantPath, diff)
+			}
+		})
+	}
+}
+
+func readWant(path string) (map[string]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	want := map[string]string{}
+	for i, line := range strings.Split(strings.TrimRight(string(b), "\n"), "\n") {
+		name, answer, ok := strings.Cut(line, ": ")
+		if !ok {
+			return nil, fmt.Errorf("%s line %d: want `part: answer`, got %s", path, i+1, line)
+		}
+		want[name] = answer
+	}
+
+	return want, nil
+}
+
+func writeWant(path string, answers map[string]string) error {
+	var b strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(answers)) {
+		fmt.Fprintf(&b, "%s: %s\n", name, answers[name])
+	}
+
+	return os.WriteFile(path, []byte(b.String()), 0o644)
+}
@@ -0,0 +1,23 @@
+package testutil_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/max-nicholson/advent-of-code-2024/lib/testutil"
+)
+
+func TestGolden(t *testing.T) {
+	testutil.Golden(t, map[string]testutil.Part{
+		"lines": testutil.Lines(func(lines []string) (int, error) {
+			return len(lines), nil
+		}),
+		"longest": testutil.Content(func(content string) (int, error) {
+			longest := 0
+			for _, line := range strings.Split(content, "\n") {
+				longest = max(longest, len(line))
+			}
+			return longest, nil
+		}),
+	})
+}
@@ -0,0 +1,47 @@
+package lib
+
+// DisjointSet is a union-find over the integers 0 to n-1, with path
+// compression and union by size
+type DisjointSet struct {
+	parent []int
+	size   []int
+}
+
+func NewDisjointSet(n int) *DisjointSet {
+	d := &DisjointSet{parent: make([]int, n), size: make([]int, n)}
+	for i := range n {
+		d.parent[i] = i
+		d.size[i] = 1
+	}
+	return d
+}
+
+// Find returns the representative of the set holding x
+func (d *DisjointSet) Find(x int) int {
+	for d.parent[x] != x {
+		d.parent[x] = d.parent[d.parent[x]]
+		x = d.parent[x]
+	}
+	return x
+}
+
+// Union joins the sets holding a and b, returning false if they were already
+// the same set
+func (d *DisjointSet) Union(a, b int) bool {
+	a, b = d.Find(a), d.Find(b)
+	if a == b {
+		return false
+	}
+
+	if d.size[a] < d.size[b] {
+		a, b = b, a
+	}
+	d.parent[b] = a
+	d.size[a] += d.size[b]
+	return true
+}
+
+// Size is the number of elements in the set holding x
+func (d *DisjointSet) Size(x int) int {
+	return d.size[d.Find(x)]
+}
@@ -0,0 +1,31 @@
+package lib_test
+
+import (
+	"testing"
+
+	"github.com/max-nicholson/advent-of-code-2024/lib"
+)
+
+func TestDisjointSet(t *testing.T) {
+	d := lib.NewDisjointSet(6)
+
+	if !d.Union(0, 1) || !d.Union(2, 3) || !d.Union(1, 3) {
+		t.Fatalf("want unions of separate sets to succeed")
+	}
+
+	if d.Union(0, 2) {
+		t.Errorf("want union within a set to fail")
+	}
+
+	if d.Find(0) != d.Find(3) {
+		t.Errorf("want 0 and 3 in the same set")
+	}
+
+	if d.Find(4) == d.Find(0) || d.Size(4) != 1 {
+		t.Errorf("want 4 on its own")
+	}
+
+	if d.Size(2) != 4 {
+		t.Errorf("got size %d, want 4", d.Size(2))
+	}
+}
@@ -3,7 +3,7 @@ package main
 import (
 	"fmt"
 	"log"
-	"sort"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -29,66 +29,245 @@ func main() {
 	fmt.Printf("part2: %d\n", part2)
 }
 
-func ParseLine(line string) (int, int, error) {
-	parts := strings.Split(line, "   ")
-	left, err := strconv.Atoi(parts[0])
-	if err != nil {
-		return 0, 0, fmt.Errorf("left location: %w", err)
+// ParseLine reads every whitespace separated location ID on a line
+func ParseLine(line string) ([]int, error) {
+	fields := strings.Fields(line)
+	ids := make([]int, len(fields))
+	for i, field := range fields {
+		id, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("column %d: %w", i, err)
+		}
+		ids[i] = id
 	}
+	return ids, nil
+}
 
-	right, err := strconv.Atoi(parts[1])
-	if err != nil {
-		return 0, 0, fmt.Errorf("right location: %w", err)
+// Metric is the distance between two location IDs of the same rank
+type Metric func(a, b int) int
+
+func Absolute(a, b int) int {
+	return lib.Abs(a - b)
+}
+
+func Squared(a, b int) int {
+	return (a - b) * (a - b)
+}
+
+// Mismatch counts ranks where the lists disagree
+func Mismatch(a, b int) int {
+	if a == b {
+		return 0
 	}
-	return left, right, nil
+	return 1
 }
 
-func Part1(lines []string) (int, error) {
-	total := 0
-	locations := len(lines)
+// LocationLists keeps each column sorted as rows are inserted, so the nth
+// smallest ID of any list is available without re-sorting
+type LocationLists struct {
+	lists    [][]int
+	counts   []map[int]int
+	trackers []*Tracker
+}
+
+// Tracker is a running Distance between two lists, kept up to date as rows
+// are inserted
+type Tracker struct {
+	a, b   int
+	metric Metric
+	total  int
+}
 
-	leftList := make([]int, locations)
-	rightList := make([]int, locations)
+// Distance is the same as LocationLists.Distance for the tracked lists, without
+// going over every pair
+func (t *Tracker) Distance() int {
+	return t.total
+}
+
+func NewLocationLists(columns int) *LocationLists {
+	l := &LocationLists{
+		lists:  make([][]int, columns),
+		counts: make([]map[int]int, columns),
+	}
+	for i := range l.counts {
+		l.counts[i] = make(map[int]int)
+	}
+	return l
+}
 
+// ParseLocationLists reads one row per line, taking the number of columns
+// from the first line
+func ParseLocationLists(lines []string) (*LocationLists, error) {
+	var l *LocationLists
 	for i, line := range lines {
-		left, right, err := ParseLine(line)
+		ids, err := ParseLine(line)
 		if err != nil {
-			return 0, fmt.Errorf("line %d: %w", i, err)
+			return nil, fmt.Errorf("line %d: %w", i, err)
+		}
+
+		if l == nil {
+			l = NewLocationLists(len(ids))
+		}
+
+		if err := l.Insert(ids...); err != nil {
+			return nil, fmt.Errorf("line %d: %w", i, err)
 		}
+	}
 
-		leftList[i] = left
-		rightList[i] = right
+	if l == nil {
+		return NewLocationLists(2), nil
 	}
+	return l, nil
+}
 
-	sort.Ints(leftList)
-	sort.Ints(rightList)
+// Insert adds one ID to each list
+func (l *LocationLists) Insert(ids ...int) error {
+	if len(ids) != len(l.lists) {
+		return fmt.Errorf("got %d location IDs, want %d", len(ids), len(l.lists))
+	}
 
-	for i := range locations {
-		total += lib.Abs(leftList[i] - rightList[i])
+	ranks := make([]int, len(ids))
+	for i, id := range ids {
+		ranks[i], _ = slices.BinarySearch(l.lists[i], id)
 	}
 
-	return total, nil
+	// pairs ranked below both new IDs keep their partners, as do pairs ranked
+	// above both (shifted up one in each list), so only the pairs in between
+	// change
+	for _, t := range l.trackers {
+		for r := min(ranks[t.a], ranks[t.b]); r < max(ranks[t.a], ranks[t.b]); r++ {
+			t.total -= t.metric(l.lists[t.a][r], l.lists[t.b][r])
+		}
+	}
+
+	for i, id := range ids {
+		l.lists[i] = slices.Insert(l.lists[i], ranks[i], id)
+		l.counts[i][id]++
+	}
+
+	for _, t := range l.trackers {
+		for r := min(ranks[t.a], ranks[t.b]); r <= max(ranks[t.a], ranks[t.b]); r++ {
+			t.total += t.metric(l.lists[t.a][r], l.lists[t.b][r])
+		}
+	}
+	return nil
 }
 
-func Part2(lines []string) (int, error) {
-	similarityScore := 0
-	totalLocations := len(lines)
+// Track starts a running Distance between lists a and b that Insert adjusts
+// as rows come in
+func (l *LocationLists) Track(a, b int, metric Metric) *Tracker {
+	t := &Tracker{a: a, b: b, metric: metric, total: l.Distance(a, b, metric)}
+	l.trackers = append(l.trackers, t)
+	return t
+}
 
-	locations := make([]int, totalLocations)
-	locationCounts := make(map[int]int, totalLocations/3)
+func (l *LocationLists) Columns() int {
+	return len(l.lists)
+}
 
-	for i, line := range lines {
-		left, right, err := ParseLine(line)
-		if err != nil {
-			return 0, fmt.Errorf("line %d: %w", i, err)
+func (l *LocationLists) Len() int {
+	if len(l.lists) == 0 {
+		return 0
+	}
+	return len(l.lists[0])
+}
+
+// Nth returns the nth smallest ID (from 0) in a list
+func (l *LocationLists) Nth(column, n int) int {
+	return l.lists[column][n]
+}
+
+// Distance pairs up the two lists smallest to largest and sums the metric
+// over each pair
+func (l *LocationLists) Distance(a, b int, metric Metric) int {
+	total := 0
+	for i := range l.Len() {
+		total += metric(l.lists[a][i], l.lists[b][i])
+	}
+	return total
+}
+
+// Similarity adds up each ID in list a multiplied by how often it appears
+// in list b
+func (l *LocationLists) Similarity(a, b int) int {
+	score := 0
+	for id, count := range l.counts[a] {
+		score += id * count * l.counts[b][id]
+	}
+	return score
+}
+
+// MedianGap is the median absolute difference between same rank IDs
+func (l *LocationLists) MedianGap(a, b int) float64 {
+	n := l.Len()
+	if n == 0 {
+		return 0
+	}
+
+	gaps := make([]int, n)
+	for i := range n {
+		gaps[i] = Absolute(l.lists[a][i], l.lists[b][i])
+	}
+	slices.Sort(gaps)
+
+	if n%2 == 1 {
+		return float64(gaps[n/2])
+	}
+	return float64(gaps[n/2-1]+gaps[n/2]) / 2
+}
+
+// Frequencies maps each ID in a list to how many times it appears
+func (l *LocationLists) Frequencies(column int) map[int]int {
+	frequencies := make(map[int]int, len(l.counts[column]))
+	for id, count := range l.counts[column] {
+		frequencies[id] = count
+	}
+	return frequencies
+}
+
+// Exclusive returns the IDs, smallest first, that appear in the given list
+// and no other
+func (l *LocationLists) Exclusive(column int) []int {
+	var ids []int
+	for id := range l.counts[column] {
+		shared := false
+		for other, counts := range l.counts {
+			if other != column && counts[id] > 0 {
+				shared = true
+				break
+			}
+		}
+
+		if !shared {
+			ids = append(ids, id)
 		}
-		locations = append(locations, left)
-		locationCounts[right] += 1
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func Part1(lines []string) (int, error) {
+	locations, err := ParseLocationLists(lines)
+	if err != nil {
+		return 0, err
+	}
+
+	if locations.Columns() < 2 {
+		return 0, fmt.Errorf("want at least 2 location lists, got %d", locations.Columns())
+	}
+
+	return locations.Distance(0, 1, Absolute), nil
+}
+
+func Part2(lines []string) (int, error) {
+	locations, err := ParseLocationLists(lines)
+	if err != nil {
+		return 0, err
 	}
 
-	for _, location := range locations {
-		similarityScore += locationCounts[location] * location
+	if locations.Columns() < 2 {
+		return 0, fmt.Errorf("want at least 2 location lists, got %d", locations.Columns())
 	}
 
-	return similarityScore, nil
+	return locations.Similarity(0, 1), nil
 }
@@ -1,36 +1,126 @@
 package main
 
 import (
-	"strings"
+	"math/rand/v2"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/max-nicholson/advent-of-code-2024/lib/testutil"
 )
 
-func TestPart1(t *testing.T) {
-	result, err := Part1(strings.Split(`3   4
-4   3
-2   5
-1   3
-3   9
-3   3`, "\n"))
-	if err != nil {
+func TestExamples(t *testing.T) {
+	testutil.Golden(t, map[string]testutil.Part{
+		"part1": testutil.Lines(Part1),
+		"part2": testutil.Lines(Part2),
+	})
+}
+
+func TestLocationListsIncremental(t *testing.T) {
+	rows := [][]int{{3, 4}, {4, 3}, {2, 5}, {1, 3}, {3, 9}, {3, 3}}
+	// running distance after each row is inserted
+	want := []int{1, 0, 3, 5, 11, 11}
+
+	locations := NewLocationLists(2)
+	running := locations.Track(0, 1, Absolute)
+	for i, row := range rows {
+		if err := locations.Insert(row...); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := running.Distance(); got != want[i] {
+			t.Errorf("after %d rows: got running distance %d, want %d", i+1, got, want[i])
+		}
+		if got := locations.Distance(0, 1, Absolute); got != want[i] {
+			t.Errorf("after %d rows: got distance %d, want %d", i+1, got, want[i])
+		}
+	}
+
+	if got := locations.Nth(1, 5); got != 9 {
+		t.Errorf("Nth(1, 5) got %d, want 9", got)
+	}
+}
+
+func TestTrackerMatchesDistance(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 1))
+	metrics := map[string]Metric{"absolute": Absolute, "squared": Squared, "mismatch": Mismatch}
+
+	locations := NewLocationLists(3)
+	if err := locations.Insert(5, 5, 5); err != nil {
 		t.Fatal(err)
 	}
-	if result != 11 {
-		t.Fatalf("expected 11, got %d", result)
+
+	type tracked struct {
+		name    string
+		a, b    int
+		metric  Metric
+		tracker *Tracker
+	}
+	var trackers []tracked
+	for _, name := range []string{"absolute", "squared", "mismatch"} {
+		for a := range 3 {
+			for b := range 3 {
+				trackers = append(trackers, tracked{name, a, b, metrics[name], locations.Track(a, b, metrics[name])})
+			}
+		}
+	}
+
+	for i := range 500 {
+		if err := locations.Insert(r.IntN(50), r.IntN(50), r.IntN(50)); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, tr := range trackers {
+			if got, want := tr.tracker.Distance(), locations.Distance(tr.a, tr.b, tr.metric); got != want {
+				t.Fatalf("after %d rows: %s distance between %d and %d got %d, want %d", i+2, tr.name, tr.a, tr.b, got, want)
+			}
+		}
 	}
 }
 
-func TestPart2(t *testing.T) {
-	result, err := Part2(strings.Split(`3   4
-4   3
-2   5
-1   3
-3   9
-3   3`, "\n"))
+func TestLocationListsReports(t *testing.T) {
+	locations, err := ParseLocationLists([]string{
+		"3   4   1",
+		"4   3   1",
+		"2   5   2",
+		"1   3   7",
+		"3   9   3",
+		"3   3   3",
+	})
 	if err != nil {
 		t.Fatal(err)
 	}
-	if result != 31 {
-		t.Fatalf("expected 31, got %d", result)
+
+	if got := locations.Columns(); got != 3 {
+		t.Fatalf("Columns() got %d, want 3", got)
+	}
+
+	// gaps between the sorted lists are 2, 1, 0, 1, 2, 5
+	if got := locations.MedianGap(0, 1); got != 1.5 {
+		t.Errorf("MedianGap(0, 1) got %v, want 1.5", got)
+	}
+
+	if got := locations.Distance(0, 1, Squared); got != 35 {
+		t.Errorf("Distance(0, 1, Squared) got %d, want 35", got)
+	}
+
+	if got := locations.Distance(0, 1, Mismatch); got != 5 {
+		t.Errorf("Distance(0, 1, Mismatch) got %d, want 5", got)
+	}
+
+	if diff := cmp.Diff(map[int]int{3: 3, 4: 1, 5: 1, 9: 1}, locations.Frequencies(1)); diff != "" {
+		t.Errorf("Frequencies(1) mismatch (-want +got):\n%s", diff)
+	}
+
+	if diff := cmp.Diff([]int{5, 9}, locations.Exclusive(1)); diff != "" {
+		t.Errorf("Exclusive(1) mismatch (-want +got):\n%s", diff)
+	}
+
+	if diff := cmp.Diff([]int{7}, locations.Exclusive(2)); diff != "" {
+		t.Errorf("Exclusive(2) mismatch (-want +got):\n%s", diff)
+	}
+
+	if got := locations.Similarity(0, 2); got != 3*3*2+2*1*1+1*1*2 {
+		t.Errorf("Similarity(0, 2) got %d, want 22", got)
 	}
 }
@@ -21,66 +21,101 @@ const (
 	Descending
 )
 
-func (r Report) IsSafe() bool {
-	levels := len(r.Levels)
-
-	var direction Direction
-	var prev int = r.Levels[0]
-	for i := 1; i < levels; i++ {
-		level := r.Levels[i]
+// Tolerance bounds the size of the step between neighbouring levels
+type Tolerance struct {
+	MinStep int
+	MaxStep int
+}
 
-		diff := lib.Abs(level - prev)
+var DefaultTolerance = Tolerance{MinStep: 1, MaxStep: 3}
 
-		if diff > 3 || diff == 0 {
-			return false
-		}
-
-		if direction == Unknown {
-			if level > prev {
-				direction = Ascending
-			} else {
-				direction = Descending
-			}
-		} else if direction == Ascending {
-			if level < prev {
-				return false
-			}
-		} else {
-			if level > prev {
-				return false
-			}
-		}
-		prev = level
+func (t Tolerance) allows(from, to int, direction Direction) bool {
+	diff := to - from
+	if direction == Descending {
+		diff = -diff
 	}
+	return diff >= t.MinStep && diff <= t.MaxStep
+}
+
+func (r Report) IsSafe() bool {
+	return r.IsSafeWithin(DefaultTolerance)
+}
 
-	return true
+func (r Report) IsSafeWithin(t Tolerance) bool {
+	_, ok := r.Dampen(t, 0)
+	return ok
 }
 
 func (r Report) IsSafeWithProblemDampener() bool {
-	// Could use BFS here, but it's more effort
-	if r.IsSafe() {
-		return true
+	_, ok := r.Dampen(DefaultTolerance, 1)
+	return ok
+}
+
+// Dampen finds the fewest levels (at most k) to remove so the rest of the
+// report is safe, returning their indexes.
+//
+// For each direction, removals[i] is the fewest removals needed for a safe
+// report ending in level i with level i kept. Only the k+1 levels before i can
+// be the previous kept level, so this is O(n*k)
+func (r Report) Dampen(t Tolerance, k int) ([]int, bool) {
+	n := len(r.Levels)
+	if n == 0 {
+		return []int{}, true
 	}
 
-	levels := len(r.Levels)
+	var best []int
+	for _, direction := range []Direction{Ascending, Descending} {
+		removed, ok := r.dampen(t, k, direction)
+		if ok && (best == nil || len(removed) < len(best)) {
+			best = removed
+		}
+	}
 
-	for i := range r.Levels {
-		dampedLevels := make([]int, levels-1)
-		var offset int
-		for j := range levels {
-			if j == i {
-				offset = 1
-			} else {
-				dampedLevels[j-offset] = r.Levels[j]
+	return best, best != nil
+}
+
+func (r Report) dampen(t Tolerance, k int, direction Direction) ([]int, bool) {
+	n := len(r.Levels)
+	removals := make([]int, n)
+	previous := make([]int, n)
+
+	for i := range n {
+		// drop everything before i
+		removals[i] = i
+		previous[i] = -1
+
+		for p := max(i-k-1, 0); p < i; p++ {
+			cost := removals[p] + i - p - 1
+			if cost < removals[i] && t.allows(r.Levels[p], r.Levels[i], direction) {
+				removals[i] = cost
+				previous[i] = p
 			}
 		}
+	}
 
-		if (Report{Levels: dampedLevels}).IsSafe() {
-			return true
+	last := -1
+	for i := max(n-k-1, 0); i < n; i++ {
+		if last == -1 || removals[i]+n-1-i < removals[last]+n-1-last {
+			last = i
 		}
 	}
 
-	return false
+	if removals[last]+n-1-last > k {
+		return nil, false
+	}
+
+	kept := make([]bool, n)
+	for i := last; i != -1; i = previous[i] {
+		kept[i] = true
+	}
+
+	removed := []int{}
+	for i, keep := range kept {
+		if !keep {
+			removed = append(removed, i)
+		}
+	}
+	return removed, true
 }
 
 func main() {
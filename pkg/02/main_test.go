@@ -1,36 +1,119 @@
 package main
 
 import (
+	"math/bits"
+	"slices"
 	"strings"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/max-nicholson/advent-of-code-2024/lib/gen"
+	"github.com/max-nicholson/advent-of-code-2024/lib/testutil"
 )
 
-func TestPart1(t *testing.T) {
-	result, err := Part1(strings.Split(`7 6 4 2 1
-1 2 7 8 9
-9 7 6 2 1
-1 3 2 4 5
-8 6 4 4 1
-1 3 6 7 9`, "\n"))
+func TestExamples(t *testing.T) {
+	testutil.Golden(t, map[string]testutil.Part{
+		"part1": testutil.Lines(Part1),
+		"part2": testutil.Lines(Part2),
+	})
+}
+
+// safeWithout brute forces every way of removing up to k levels
+func safeWithout(levels []int, t Tolerance, k int) int {
+	best := -1
+	for mask := range 1 << len(levels) {
+		removed := bits.OnesCount(uint(mask))
+		if removed > k || (best != -1 && removed >= best) {
+			continue
+		}
+
+		var kept []int
+		for i, level := range levels {
+			if mask&(1<<i) == 0 {
+				kept = append(kept, level)
+			}
+		}
+
+		for _, direction := range []Direction{Ascending, Descending} {
+			safe := true
+			for i := 1; i < len(kept); i++ {
+				if !t.allows(kept[i-1], kept[i], direction) {
+					safe = false
+					break
+				}
+			}
+			if safe {
+				best = removed
+			}
+		}
+	}
+	return best
+}
+
+func TestDampenMatchesBruteForce(t *testing.T) {
+	tolerances := []Tolerance{DefaultTolerance, {MinStep: 0, MaxStep: 2}, {MinStep: 2, MaxStep: 5}}
+
+	input, err := gen.Generate(2, 200, 1)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if result != 2 {
-		t.Fatalf("expected 2, got %d", result)
+
+	var reports []Report
+	for _, line := range strings.Split(input, "\n") {
+		report, err := ParseReport(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reports = append(reports, *report)
+	}
+
+	// reports no longer than k+1, where removing everything but one level
+	// isn't always the fewest removals
+	short := []Report{{}, {Levels: []int{1}}, {Levels: []int{1, 2}}, {Levels: []int{1, 2, 3}}, {Levels: []int{3, 1, 2}}, {Levels: []int{1, 9, 2}}}
+	for _, report := range reports[:20] {
+		for n := range 5 {
+			short = append(short, Report{Levels: report.Levels[:min(n, len(report.Levels))]})
+		}
+	}
+	reports = append(reports, short...)
+
+	for i, report := range reports {
+		for _, tolerance := range tolerances {
+			for k := range 4 {
+				removed, ok := report.Dampen(tolerance, k)
+				want := safeWithout(report.Levels, tolerance, k)
+
+				if ok != (want != -1) || (ok && len(removed) != want) {
+					t.Fatalf("report %d %v %+v k=%d: got %v %v, want %d removals", i, report.Levels, tolerance, k, removed, ok, want)
+				}
+
+				if !ok {
+					continue
+				}
+
+				var kept []int
+				for j, level := range report.Levels {
+					if !slices.Contains(removed, j) {
+						kept = append(kept, level)
+					}
+				}
+				if !(Report{Levels: kept}).IsSafeWithin(tolerance) {
+					t.Fatalf("report %d %v %+v k=%d: removing %v leaves unsafe %v", i, report.Levels, tolerance, k, removed, kept)
+				}
+			}
+		}
 	}
 }
 
-func TestPart2(t *testing.T) {
-	result, err := Part2(strings.Split(`7 6 4 2 1
-1 2 7 8 9
-9 7 6 2 1
-1 3 2 4 5
-8 6 4 4 1
-1 3 6 7 9`, "\n"))
-	if err != nil {
-		t.Fatal(err)
+func TestDampenReportsLevels(t *testing.T) {
+	report := Report{Levels: []int{1, 2, 7, 3, 4}}
+
+	removed, ok := report.Dampen(DefaultTolerance, 1)
+	if !ok {
+		t.Fatal("want report to be safe after removing a level")
 	}
-	if result != 4 {
-		t.Fatalf("expected 4, got %d", result)
+
+	if diff := cmp.Diff([]int{2}, removed); diff != "" {
+		t.Errorf("Dampen() mismatch (-want +got):\n%s", diff)
 	}
 }
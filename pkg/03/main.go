@@ -1,87 +1,254 @@
 package main
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"io"
+	"iter"
 	"log"
-	"regexp"
-	"strconv"
-
-	"github.com/max-nicholson/advent-of-code-2024/lib"
+	"os"
 )
 
 func main() {
-	lines, err := lib.ReadLines("pkg/03/input.txt")
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	part1, err := Part1(lines)
+	part1, err := runFile("pkg/03/input.txt", Part1)
 	if err != nil {
 		log.Fatal(err)
 	}
 	fmt.Printf("part1: %d\n", part1)
 
-	part2, err := Part2(lines)
+	part2, err := runFile("pkg/03/input.txt", Part2)
 	if err != nil {
 		log.Fatal(err)
 	}
 	fmt.Printf("part2: %d\n", part2)
 }
 
-func ParseInstruction(match []string) (int, int, error) {
-	a, err := strconv.Atoi(match[1])
+func runFile(path string, part func(io.Reader) (int, error)) (int, error) {
+	f, err := os.Open(path)
 	if err != nil {
-		return 0, 0, err
+		return 0, err
 	}
-	b, err := strconv.Atoi(match[2])
-	if err != nil {
-		return 0, 0, err
+	defer f.Close()
+
+	return part(f)
+}
+
+// Machine is the state instructions act on
+type Machine struct {
+	Enabled bool
+	Total   int
+}
+
+// Instruction is a call like mul(2,4) found in corrupted memory. Names must
+// not contain digits, commas or brackets
+type Instruction struct {
+	Name  string
+	Arity int
+	// Control instructions still run while the machine is disabled
+	Control bool
+	Exec    func(m *Machine, args []int)
+}
+
+var (
+	Mul = Instruction{Name: "mul", Arity: 2, Exec: func(m *Machine, args []int) {
+		m.Total += args[0] * args[1]
+	}}
+	Add = Instruction{Name: "add", Arity: 2, Exec: func(m *Machine, args []int) {
+		m.Total += args[0] + args[1]
+	}}
+	Sub = Instruction{Name: "sub", Arity: 2, Exec: func(m *Machine, args []int) {
+		m.Total += args[0] - args[1]
+	}}
+	Do = Instruction{Name: "do", Control: true, Exec: func(m *Machine, args []int) {
+		m.Enabled = true
+	}}
+	Dont = Instruction{Name: "don't", Control: true, Exec: func(m *Machine, args []int) {
+		m.Enabled = false
+	}}
+)
+
+// Registry is the set of instructions the lexer recognises, by name
+type Registry map[string]Instruction
+
+func NewRegistry(instructions ...Instruction) Registry {
+	registry := make(Registry, len(instructions))
+	for _, instruction := range instructions {
+		registry.Register(instruction)
 	}
+	return registry
+}
 
-	return a, b, nil
+func (r Registry) Register(instruction Instruction) {
+	r[instruction.Name] = instruction
 }
 
-func Part1(lines []string) (int, error) {
-	total := 0
+// Token is a well formed instruction call and the byte offset of its name
+type Token struct {
+	Offset int
+	Name   string
+	Args   []int
+}
 
-	re := regexp.MustCompile(`mul\(([0-9]{1,3}),([0-9]{1,3})\)`)
+func (t Token) String() string {
+	return fmt.Sprintf("%d: %s%v", t.Offset, t.Name, t.Args)
+}
 
-	for i, line := range lines {
-		matches := re.FindAllStringSubmatch(line, -1)
-		for _, match := range matches {
-			a, b, err := ParseInstruction(match)
-			if err != nil {
-				return 0, fmt.Errorf("line %d, %s: %w", i, match[0], err)
+// Lexer reads tokens one byte at a time, keeping only enough of the input to
+// recognise the longest instruction name
+type Lexer struct {
+	r        *bufio.Reader
+	registry Registry
+	offset   int
+	recent   []byte
+	longest  int
+}
+
+func NewLexer(r io.Reader, registry Registry) *Lexer {
+	longest := 0
+	for name := range registry {
+		longest = max(longest, len(name))
+	}
+
+	return &Lexer{r: bufio.NewReader(r), registry: registry, longest: longest}
+}
+
+// Next returns the next well formed call, or io.EOF at the end of the input
+func (l *Lexer) Next() (Token, error) {
+	for {
+		b, err := l.r.ReadByte()
+		if err != nil {
+			return Token{}, err
+		}
+		l.offset++
+
+		if b != '(' {
+			l.recent = append(l.recent, b)
+			if len(l.recent) > l.longest {
+				l.recent = l.recent[len(l.recent)-l.longest:]
 			}
-			total += a * b
+			continue
+		}
+
+		name, ok := l.name()
+		l.recent = l.recent[:0]
+		if !ok {
+			continue
+		}
+		start := l.offset - 1 - len(name)
+
+		args, ok, err := l.args()
+		if err != nil {
+			return Token{}, err
+		}
+
+		if ok && len(args) == l.registry[name].Arity {
+			return Token{Offset: start, Name: name, Args: args}, nil
 		}
 	}
+}
 
-	return total, nil
+// name finds the longest instruction name the recent bytes end with
+func (l *Lexer) name() (string, bool) {
+	for i := range l.recent {
+		if _, ok := l.registry[string(l.recent[i:])]; ok {
+			return string(l.recent[i:]), true
+		}
+	}
+	return "", false
 }
 
-func Part2(lines []string) (int, error) {
-	total := 0
+// args reads 1-3 digit arguments up to the closing bracket. The byte that
+// breaks the pattern is unread so it can start the next instruction
+func (l *Lexer) args() ([]int, bool, error) {
+	args := []int{}
+	arg, digits := 0, 0
+
+	for {
+		b, err := l.r.ReadByte()
+		if err != nil {
+			return nil, false, err
+		}
+		l.offset++
+
+		switch {
+		case b >= '0' && b <= '9' && digits < 3:
+			arg = arg*10 + int(b-'0')
+			digits++
+			continue
+		case b == ',' && digits > 0:
+			args = append(args, arg)
+			arg, digits = 0, 0
+			continue
+		case b == ')' && digits > 0:
+			return append(args, arg), true, nil
+		case b == ')' && len(args) == 0:
+			return args, true, nil
+		}
+
+		if err := l.r.UnreadByte(); err != nil {
+			return nil, false, err
+		}
+		l.offset--
+		return nil, false, nil
+	}
+}
 
-	re := regexp.MustCompile(`mul\(([0-9]{1,3}),([0-9]{1,3})\)|do\(\)|don't\(\)`)
+// Interpreter runs the instructions in its registry against a machine, which
+// starts enabled
+type Interpreter struct {
+	Registry Registry
+	Machine
+}
 
-	var enabled = true
-	for i, line := range lines {
-		matches := re.FindAllStringSubmatch(line, -1)
-		for _, match := range matches {
-			if match[0] == "do()" {
-				enabled = true
-			} else if match[0] == "don't()" {
-				enabled = false
-			} else if enabled {
-				a, b, err := ParseInstruction(match)
-				if err != nil {
-					return 0, fmt.Errorf("line %d, %s: %w", i, match[0], err)
-				}
-				total += a * b
+func NewInterpreter(instructions ...Instruction) *Interpreter {
+	return &Interpreter{
+		Registry: NewRegistry(instructions...),
+		Machine:  Machine{Enabled: true},
+	}
+}
+
+// Execute yields each instruction as it's executed, skipping any found while
+// the machine is disabled
+func (in *Interpreter) Execute(r io.Reader) iter.Seq2[Token, error] {
+	return func(yield func(Token, error) bool) {
+		lexer := NewLexer(r, in.Registry)
+		for {
+			token, err := lexer.Next()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				yield(token, err)
+				return
+			}
+
+			instruction := in.Registry[token.Name]
+			if !in.Enabled && !instruction.Control {
+				continue
+			}
+
+			instruction.Exec(&in.Machine, token.Args)
+			if !yield(token, nil) {
+				return
 			}
 		}
 	}
+}
+
+func (in *Interpreter) Run(r io.Reader) (int, error) {
+	for _, err := range in.Execute(r) {
+		if err != nil {
+			return 0, err
+		}
+	}
+	return in.Total, nil
+}
+
+func Part1(r io.Reader) (int, error) {
+	return NewInterpreter(Mul).Run(r)
+}
 
-	return total, nil
+func Part2(r io.Reader) (int, error) {
+	return NewInterpreter(Mul, Do, Dont).Run(r)
 }
@@ -3,24 +3,85 @@ package main
 import (
 	"strings"
 	"testing"
+	"testing/iotest"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/max-nicholson/advent-of-code-2024/lib/testutil"
 )
 
-func TestPart1(t *testing.T) {
-	result, err := Part1(strings.Split(`xmul(2,4)%&mul[3,7]!@^do_not_mul(5,5)+mul(32,64]then(mul(11,8)mul(8,5))`, "\n"))
-	if err != nil {
-		t.Fatal(err)
+func TestExamples(t *testing.T) {
+	testutil.Golden(t, map[string]testutil.Part{
+		"part1": testutil.Content(func(input string) (int, error) { return Part1(strings.NewReader(input)) }),
+		"part2": testutil.Content(func(input string) (int, error) { return Part2(strings.NewReader(input)) }),
+	})
+}
+
+func TestExecuteOffsets(t *testing.T) {
+	input := "xmul(2,4)&mul[3,7]!^don't()_mul(5,5)+mul(32,64](mul(11,8)undo()?mul(8,5))"
+
+	var got []Token
+	interpreter := NewInterpreter(Mul, Do, Dont)
+	for token, err := range interpreter.Execute(iotest.OneByteReader(strings.NewReader(input))) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, token)
+	}
+
+	want := []Token{
+		{Offset: 1, Name: "mul", Args: []int{2, 4}},
+		{Offset: 20, Name: "don't", Args: []int{}},
+		{Offset: 59, Name: "do", Args: []int{}},
+		{Offset: 64, Name: "mul", Args: []int{8, 5}},
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Execute() mismatch (-want +got):\n%s", diff)
 	}
-	if result != 161 {
-		t.Fatalf("expected 161, got %d", result)
+
+	for _, token := range want {
+		if !strings.HasPrefix(input[token.Offset:], token.Name+"(") {
+			t.Errorf("offset %d doesn't point at %s", token.Offset, token.Name)
+		}
 	}
 }
 
-func TestPart2(t *testing.T) {
-	result, err := Part2(strings.Split(`xmul(2,4)&mul[3,7]!^don't()_mul(5,5)+mul(32,64](mul(11,8)undo()?mul(8,5))`, "\n"))
+func TestLexerEdgeCases(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		want  int
+	}{
+		{name: "nested start", input: "mul(2,mul(3,4)", want: 12},
+		{name: "four digits", input: "mul(1234,5)mul(123,5)", want: 615},
+		{name: "wrong arity", input: "mul(2)mul(2,3,4)mul(2,3)", want: 6},
+		{name: "spaces", input: "mul ( 2, 3)mul(2 ,3)", want: 0},
+		{name: "across lines", input: "mul(1,1)\nmul(2,2)", want: 5},
+		{name: "trailing", input: "mul(2,3", want: 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := Part1(strings.NewReader(c.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != c.want {
+				t.Errorf("got %d, want %d", got, c.want)
+			}
+		})
+	}
+}
+
+func TestRegistryExtraInstructions(t *testing.T) {
+	interpreter := NewInterpreter(Mul, Add, Sub, Do, Dont)
+
+	got, err := interpreter.Run(strings.NewReader("add(1,2)sub(10,4)don't()add(100,100)do()mul(2,3)xsub(1,9)"))
 	if err != nil {
 		t.Fatal(err)
 	}
-	if result != 48 {
-		t.Fatalf("expected 48, got %d", result)
+
+	if want := 3 + 6 + 6 - 8; got != want {
+		t.Errorf("got %d, want %d", got, want)
 	}
 }
@@ -3,6 +3,8 @@ package main
 import (
 	"fmt"
 	"log"
+	"slices"
+	"strings"
 
 	"github.com/max-nicholson/advent-of-code-2024/lib"
 )
@@ -26,74 +28,275 @@ func main() {
 	fmt.Printf("part2: %d\n", part2)
 }
 
-func Part1(lines []string) (int, error) {
-	total := 0
+type Point struct {
+	Row    int
+	Column int
+}
 
-	rows := len(lines)
-	columns := len(lines[0])
-	var directions = [][2]int{{1, -1}, {1, 1}, {-1, 1}, {-1, -1}, {0, 1}, {1, 0}, {0, -1}, {-1, 0}}
+func (p Point) Add(other Point) Point {
+	return Point{Row: p.Row + other.Row, Column: p.Column + other.Column}
+}
 
-	for row, chars := range lines {
-		for column, c := range chars {
-			if c != 'X' {
-				continue
-			}
+func (p Point) Scale(n int) Point {
+	return Point{Row: p.Row * n, Column: p.Column * n}
+}
 
-			for _, direction := range directions {
-				dx := direction[0]
-				dy := direction[1]
+var Directions = []Point{
+	{-1, -1}, {-1, 0}, {-1, 1},
+	{0, -1}, {0, 1},
+	{1, -1}, {1, 0}, {1, 1},
+}
 
-				for i, c := range []byte{'M', 'A', 'S'} {
-					y := row + dy*(i+1)
-					x := column + dx*(i+1)
+// Grid is a rectangular word search, indexed [row][column]
+type Grid []string
 
-					if x < 0 || x > rows-1 {
-						break
-					}
+func (g Grid) Rows() int {
+	return len(g)
+}
 
-					if y < 0 || y > columns-1 {
-						break
-					}
+func (g Grid) Columns() int {
+	if len(g) == 0 {
+		return 0
+	}
+	return len(g[0])
+}
 
-					if lines[y][x] != c {
-						break
-					}
+func (g Grid) InBounds(p Point) bool {
+	return p.Row >= 0 && p.Row < g.Rows() && p.Column >= 0 && p.Column < g.Columns()
+}
+
+func (g Grid) At(p Point) byte {
+	return g[p.Row][p.Column]
+}
+
+// Match is a word read from Start, one step of Direction per letter
+type Match struct {
+	Word      string
+	Start     Point
+	Direction Point
+}
+
+// FindWords finds every word in every direction. Each line of the grid in
+// each direction is fed through a single Aho-Corasick automaton, so the cost
+// doesn't grow with the number of words. Palindromes (and single letters) are
+// found once for each direction they read in
+func (g Grid) FindWords(words ...string) ([]Match, error) {
+	automaton, err := NewAutomaton(words)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []Match
+	for _, direction := range Directions {
+		back := direction.Scale(-1)
 
-					if i == 2 {
-						total += 1
+		for row := range g.Rows() {
+			for column := range g.Columns() {
+				start := Point{Row: row, Column: column}
+				// only start at the edge, where the line begins
+				if g.InBounds(start.Add(back)) {
+					continue
+				}
+
+				state := 0
+				for p := start; g.InBounds(p); p = p.Add(direction) {
+					state = automaton.Step(state, g.At(p))
+					for _, word := range automaton.Output(state) {
+						matches = append(matches, Match{
+							Word:      words[word],
+							Start:     p.Add(direction.Scale(1 - len(words[word]))),
+							Direction: direction,
+						})
 					}
 				}
 			}
+		}
+	}
+
+	return matches, nil
+}
+
+// Automaton is an Aho-Corasick trie, where each state is a node and failure
+// links jump to the longest proper suffix that's also in the trie
+type Automaton struct {
+	next    []map[byte]int
+	fail    []int
+	outputs [][]int
+}
+
+// NewAutomaton builds the trie for the words. An empty word would match
+// everywhere, so it's an error
+func NewAutomaton(words []string) (*Automaton, error) {
+	a := &Automaton{
+		next:    []map[byte]int{{}},
+		fail:    []int{0},
+		outputs: [][]int{nil},
+	}
+
+	for i, word := range words {
+		if word == "" {
+			return nil, fmt.Errorf("word %d is empty", i)
+		}
+
+		state := 0
+		for j := range len(word) {
+			next, ok := a.next[state][word[j]]
+			if !ok {
+				next = len(a.next)
+				a.next = append(a.next, map[byte]int{})
+				a.fail = append(a.fail, 0)
+				a.outputs = append(a.outputs, nil)
+				a.next[state][word[j]] = next
+			}
+			state = next
+		}
+		a.outputs[state] = append(a.outputs[state], i)
+	}
+
+	// breadth first, so a state's failure link is finished before its children
+	queue := []int{}
+	for _, child := range a.next[0] {
+		queue = append(queue, child)
+	}
 
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+
+		for c, child := range a.next[state] {
+			a.fail[child] = a.Step(a.fail[state], c)
+			a.outputs[child] = append(a.outputs[child], a.outputs[a.fail[child]]...)
+			queue = append(queue, child)
 		}
 	}
 
-	return total, nil
+	return a, nil
 }
 
-func Part2(lines []string) (int, error) {
-	total := 0
+func (a *Automaton) Step(state int, c byte) int {
+	for {
+		if next, ok := a.next[state][c]; ok {
+			return next
+		}
+		if state == 0 {
+			return 0
+		}
+		state = a.fail[state]
+	}
+}
+
+// Output lists the indexes of the words ending at this state
+func (a *Automaton) Output(state int) []int {
+	return a.outputs[state]
+}
 
-	rows := len(lines)
-	columns := len(lines[0])
+// Wildcard matches any letter in a Stencil
+const Wildcard = '.'
+
+// Stencil is a 2D pattern of letters, indexed [row][column]
+type Stencil []string
+
+// ParseStencil reads a stencil with one row per line. Every row must be the
+// same length, so it can be rotated
+func ParseStencil(s string) (Stencil, error) {
+	stencil := Stencil(strings.Split(s, "\n"))
+	if len(stencil[0]) == 0 {
+		return nil, fmt.Errorf("stencil is empty")
+	}
+	for row, line := range stencil {
+		if len(line) != len(stencil[0]) {
+			return nil, fmt.Errorf("stencil row %d is %d wide, want %d", row, len(line), len(stencil[0]))
+		}
+	}
+	return stencil, nil
+}
+
+func (s Stencil) String() string {
+	return strings.Join(s, "\n")
+}
+
+func (s Stencil) rotate() Stencil {
+	rows, columns := len(s), len(s[0])
+	rotated := make(Stencil, columns)
+	for column := range columns {
+		line := make([]byte, rows)
+		for row := range rows {
+			line[rows-1-row] = s[row][column]
+		}
+		rotated[column] = string(line)
+	}
+	return rotated
+}
+
+func (s Stencil) reflect() Stencil {
+	reflected := make(Stencil, len(s))
+	for row, line := range s {
+		reflected[row] = lib.Reverse(line)
+	}
+	return reflected
+}
 
-	for row := 1; row < rows-1; row++ {
-		for column := 1; column < columns-1; column++ {
-			if lines[row][column] != 'A' {
-				continue
+// Variants returns each distinct rotation and reflection of the stencil
+func (s Stencil) Variants() []Stencil {
+	var variants []Stencil
+	for _, base := range []Stencil{s, s.reflect()} {
+		for range 4 {
+			if !slices.ContainsFunc(variants, func(v Stencil) bool { return slices.Equal(v, base) }) {
+				variants = append(variants, base)
 			}
+			base = base.rotate()
+		}
+	}
+	return variants
+}
+
+// Placement is a variant of a stencil matched with its top left corner at At
+type Placement struct {
+	At      Point
+	Stencil Stencil
+}
+
+// FindStencil matches every rotation and reflection of the stencil
+func (g Grid) FindStencil(stencil Stencil) []Placement {
+	var placements []Placement
+	for _, variant := range stencil.Variants() {
+		rows, columns := len(variant), len(variant[0])
+		for row := 0; row+rows <= g.Rows(); row++ {
+			for column := 0; column+columns <= g.Columns(); column++ {
+				if g.matches(variant, row, column) {
+					placements = append(placements, Placement{At: Point{Row: row, Column: column}, Stencil: variant})
+				}
+			}
+		}
+	}
+	return placements
+}
 
-			if lines[row-1][column-1] == 'M' && lines[row+1][column-1] == 'M' && lines[row-1][column+1] == 'S' && lines[row+1][column+1] == 'S' {
-				total += 1
-			} else if lines[row-1][column-1] == 'S' && lines[row+1][column-1] == 'S' && lines[row-1][column+1] == 'M' && lines[row+1][column+1] == 'M' {
-				total += 1
-			} else if lines[row-1][column-1] == 'M' && lines[row+1][column-1] == 'S' && lines[row-1][column+1] == 'M' && lines[row+1][column+1] == 'S' {
-				total += 1
-			} else if lines[row-1][column-1] == 'S' && lines[row+1][column-1] == 'M' && lines[row-1][column+1] == 'S' && lines[row+1][column+1] == 'M' {
-				total += 1
+func (g Grid) matches(stencil Stencil, row, column int) bool {
+	for r, line := range stencil {
+		for c := range len(line) {
+			if line[c] != Wildcard && line[c] != g[row+r][column+c] {
+				return false
 			}
 		}
 	}
+	return true
+}
+
+var XMAS = Stencil{
+	"M.S",
+	".A.",
+	"M.S",
+}
+
+func Part1(lines []string) (int, error) {
+	matches, err := Grid(lines).FindWords("XMAS")
+	if err != nil {
+		return 0, err
+	}
+	return len(matches), nil
+}
 
-	return total, nil
+func Part2(lines []string) (int, error) {
+	return len(Grid(lines).FindStencil(XMAS)), nil
 }
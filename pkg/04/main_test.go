@@ -1,44 +1,128 @@
 package main
 
 import (
-	"strings"
+	"fmt"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/max-nicholson/advent-of-code-2024/lib/testutil"
 )
 
-func TestPart1(t *testing.T) {
-	result, err := Part1(strings.Split(`MMMSXXMASM
-MSAMXMSMSA
-AMXSXMAAMM
-MSAMASMSMX
-XMASAMXAMM
-XXAMMXXAMA
-SMSMSASXSS
-SAXAMASAAA
-MAMMMXMMMM
-MXMXAXMASX`, "\n"))
+func TestExamples(t *testing.T) {
+	testutil.Golden(t, map[string]testutil.Part{
+		"part1": testutil.Lines(Part1),
+		"part2": testutil.Lines(Part2),
+	})
+}
+
+func TestFindWordsRectangular(t *testing.T) {
+	grid := Grid{
+		"XMASAMX",
+		"MM.....",
+	}
+
+	got, err := grid.FindWords("XMAS", "AM", "MM")
 	if err != nil {
 		t.Fatal(err)
 	}
-	if result != 18 {
-		t.Fatalf("expected 18, got %d", result)
+	want := []Match{
+		{Word: "MM", Start: Point{1, 1}, Direction: Point{-1, 0}},
+		{Word: "MM", Start: Point{1, 0}, Direction: Point{-1, 1}},
+		{Word: "AM", Start: Point{0, 4}, Direction: Point{0, 1}},
+		{Word: "XMAS", Start: Point{0, 0}, Direction: Point{0, 1}},
+		{Word: "MM", Start: Point{0, 1}, Direction: Point{1, -1}},
+		{Word: "MM", Start: Point{0, 1}, Direction: Point{1, 0}},
+		{Word: "AM", Start: Point{0, 2}, Direction: Point{0, -1}},
+		{Word: "AM", Start: Point{0, 2}, Direction: Point{1, -1}},
+		{Word: "XMAS", Start: Point{0, 6}, Direction: Point{0, -1}},
+		{Word: "MM", Start: Point{1, 0}, Direction: Point{0, 1}},
+		{Word: "MM", Start: Point{1, 1}, Direction: Point{0, -1}},
+	}
+
+	sortMatches := cmpopts.SortSlices(func(a, b Match) bool { return fmt.Sprint(a) < fmt.Sprint(b) })
+	if diff := cmp.Diff(want, got, sortMatches); diff != "" {
+		t.Errorf("FindWords() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAutomatonOverlappingWords(t *testing.T) {
+	grid := Grid{"SHERS"}
+
+	matches, err := grid.FindWords("HE", "SHE", "HERS", "S")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]int{}
+	for _, match := range matches {
+		if match.Direction == (Point{0, 1}) {
+			got[match.Word]++
+		}
+	}
+
+	if diff := cmp.Diff(map[string]int{"HE": 1, "SHE": 1, "HERS": 1, "S": 2}, got); diff != "" {
+		t.Errorf("FindWords() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestStencilVariants(t *testing.T) {
+	cases := []struct {
+		name    string
+		stencil Stencil
+		want    int
+	}{
+		{name: "x-mas", stencil: XMAS, want: 4},
+		{name: "square", stencil: Stencil{"AA", "AA"}, want: 1},
+		{name: "line", stencil: Stencil{"ABC"}, want: 4},
+		{name: "l shape", stencil: Stencil{"A.", "A.", "AB"}, want: 8},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := len(c.stencil.Variants()); got != c.want {
+				t.Errorf("Variants() got %d, want %d", got, c.want)
+			}
+		})
 	}
 }
 
-func TestPart2(t *testing.T) {
-	result, err := Part2(strings.Split(`MMMSXXMASM
-MSAMXMSMSA
-AMXSXMAAMM
-MSAMASMSMX
-XMASAMXAMM
-XXAMMXXAMA
-SMSMSASXSS
-SAXAMASAAA
-MAMMMXMMMM
-MXMXAXMASX`, "\n"))
+func TestFindStencilRectangular(t *testing.T) {
+	grid := Grid{
+		"M.M.S.S",
+		".A...A.",
+		"S.S.M.M",
+	}
+
+	got := grid.FindStencil(XMAS)
+	want := []Placement{
+		{At: Point{0, 0}, Stencil: Stencil{"M.M", ".A.", "S.S"}},
+		{At: Point{0, 4}, Stencil: Stencil{"S.S", ".A.", "M.M"}},
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("FindStencil() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseStencil(t *testing.T) {
+	stencil, err := ParseStencil("M.S\n.A.\nM.S")
 	if err != nil {
 		t.Fatal(err)
 	}
-	if result != 9 {
-		t.Fatalf("expected 9, got %d", result)
+	if diff := cmp.Diff(XMAS, stencil); diff != "" {
+		t.Errorf("ParseStencil() mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, s := range []string{"", "AB\nA", "A\nAB", "AB\n\nAB"} {
+		if _, err := ParseStencil(s); err == nil {
+			t.Errorf("ParseStencil(%q) want error", s)
+		}
+	}
+}
+
+func TestFindWordsEmptyWord(t *testing.T) {
+	if _, err := (Grid{"XMAS"}).FindWords("XMAS", ""); err == nil {
+		t.Error("FindWords() want error for an empty word")
 	}
 }
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"container/heap"
 	"fmt"
+	"iter"
 	"log"
 	"slices"
 	"strconv"
@@ -29,9 +31,12 @@ func main() {
 	fmt.Printf("part2: %d\n", part2)
 }
 
-func ParseRules(raw string) (map[int]map[int]struct{}, error) {
+// Rules maps each page to the pages that must come after it
+type Rules map[int]map[int]struct{}
+
+func ParseRules(raw string) (Rules, error) {
 	lines := strings.Split(raw, "\n")
-	rules := make(map[int]map[int]struct{}, len(lines))
+	rules := make(Rules, len(lines))
 
 	for i, line := range lines {
 		parts := strings.Split(line, "|")
@@ -79,39 +84,183 @@ func ParseUpdates(raw string) ([][]int, error) {
 	return updates, nil
 }
 
-func Order(rules map[int]map[int]struct{}, _update []int) []int {
-	update := make([]int, len(_update))
-	copy(update, _update)
+func (r Rules) Before(a, b int) bool {
+	_, ok := r[a][b]
+	return ok
+}
+
+// Violation is a rule broken by an update, with After printed at index
+// AfterIndex ahead of Before at BeforeIndex
+type Violation struct {
+	Before      int
+	After       int
+	BeforeIndex int
+	AfterIndex  int
+}
 
-	slices.SortFunc(update, func(a, b int) int {
-		if afterA, ok := rules[a]; ok {
-			_, ok := afterA[b]
-			if ok {
-				return -1
+func (v Violation) String() string {
+	return fmt.Sprintf("rule %d|%d broken: %d is page %d but %d is page %d", v.Before, v.After, v.After, v.AfterIndex+1, v.Before, v.BeforeIndex+1)
+}
+
+// Violation explains why an update is out of order, with the first page
+// printed too late and the earliest page it should have come before
+func (r Rules) Violation(update []int) (Violation, bool) {
+	for i, page := range update {
+		for j, earlier := range update[:i] {
+			if r.Before(page, earlier) {
+				return Violation{Before: page, After: earlier, BeforeIndex: i, AfterIndex: j}, true
 			}
 		}
-		if afterB, ok := rules[b]; ok {
-			if _, ok := afterB[a]; ok {
-				return 1
+	}
+	return Violation{}, false
+}
+
+func (r Rules) InOrder(update []int) bool {
+	_, ok := r.Violation(update)
+	return !ok
+}
+
+// CycleError is returned when the rules for an update's pages can't be
+// satisfied. Pages is the chain of rules, starting and ending on the same page
+type CycleError struct {
+	Pages []int
+}
+
+func (e *CycleError) Error() string {
+	chain := make([]string, len(e.Pages))
+	for i, page := range e.Pages {
+		chain[i] = strconv.Itoa(page)
+	}
+	return fmt.Sprintf("rules form a cycle: %s", strings.Join(chain, " -> "))
+}
+
+// graph restricts the rules to the pages in an update, by index
+func (r Rules) graph(update []int) (after [][]int, before [][]int) {
+	after = make([][]int, len(update))
+	before = make([][]int, len(update))
+	for i, a := range update {
+		for j, b := range update {
+			if r.Before(a, b) {
+				after[i] = append(after[i], j)
+				before[j] = append(before[j], i)
 			}
 		}
+	}
+	return after, before
+}
 
-		return 0
-	})
+// Sort orders an update with Kahn's algorithm, only using the rules between
+// pages in the update. Pages that are free to go in either order keep their
+// original order
+func (r Rules) Sort(update []int) ([]int, error) {
+	after, before := r.graph(update)
 
-	return update
+	incoming := make([]int, len(update))
+	for i := range update {
+		incoming[i] = len(before[i])
+	}
+
+	ready := &lib.PriorityQueue[int]{}
+	for i := range update {
+		if incoming[i] == 0 {
+			heap.Push(ready, &lib.PriorityQueueItem[int]{Value: i, Priority: i})
+		}
+	}
+
+	ordered := make([]int, 0, len(update))
+	for ready.Len() > 0 {
+		i := heap.Pop(ready).(*lib.PriorityQueueItem[int]).Value
+		ordered = append(ordered, update[i])
+
+		for _, j := range after[i] {
+			incoming[j]--
+			if incoming[j] == 0 {
+				heap.Push(ready, &lib.PriorityQueueItem[int]{Value: j, Priority: j})
+			}
+		}
+	}
+
+	if len(ordered) < len(update) {
+		return nil, &CycleError{Pages: cycle(update, before, incoming)}
+	}
+
+	return ordered, nil
 }
 
-func InOrder(rules map[int]map[int]struct{}, update []int) bool {
-	seen := make(map[int]struct{})
-	for _, page := range update {
-		after, ok := rules[page]
-		if ok && lib.Intersect(after, seen) {
-			return false
+// cycle walks backwards from a page Kahn's algorithm couldn't place. Every
+// such page has an unplaced page before it, so the walk must loop
+func cycle(update []int, before [][]int, incoming []int) []int {
+	i := slices.IndexFunc(incoming, func(n int) bool { return n > 0 })
+
+	visited := make(map[int]int)
+	var path []int
+	for {
+		if start, ok := visited[i]; ok {
+			path = path[start:]
+			break
+		}
+		visited[i] = len(path)
+		path = append(path, i)
+
+		i = before[i][slices.IndexFunc(before[i], func(j int) bool { return incoming[j] > 0 })]
+	}
+
+	// path follows rules backwards, so reverse it into before -> after order
+	pages := make([]int, 0, len(path)+1)
+	for k := len(path) - 1; k >= 0; k-- {
+		pages = append(pages, update[path[k]])
+	}
+	return append(pages, pages[0])
+}
+
+// Orderings enumerates every order of the update's pages that satisfies the
+// rules. There can be up to n! of them, so this is only for small updates
+func (r Rules) Orderings(update []int) iter.Seq[[]int] {
+	return func(yield func([]int) bool) {
+		after, before := r.graph(update)
+
+		incoming := make([]int, len(update))
+		for i := range update {
+			incoming[i] = len(before[i])
+		}
+
+		placed := make([]bool, len(update))
+		ordered := make([]int, 0, len(update))
+
+		var visit func() bool
+		visit = func() bool {
+			if len(ordered) == len(update) {
+				return yield(slices.Clone(ordered))
+			}
+
+			for i := range update {
+				if placed[i] || incoming[i] > 0 {
+					continue
+				}
+
+				placed[i] = true
+				ordered = append(ordered, update[i])
+				for _, j := range after[i] {
+					incoming[j]--
+				}
+
+				more := visit()
+
+				for _, j := range after[i] {
+					incoming[j]++
+				}
+				ordered = ordered[:len(ordered)-1]
+				placed[i] = false
+
+				if !more {
+					return false
+				}
+			}
+			return true
 		}
-		seen[page] = struct{}{}
+
+		visit()
 	}
-	return true
 }
 
 func Part1(content string) (int, error) {
@@ -130,7 +279,7 @@ func Part1(content string) (int, error) {
 	}
 
 	for _, update := range updates {
-		if InOrder(rules, update) {
+		if rules.InOrder(update) {
 			total += update[len(update)/2]
 		}
 	}
@@ -153,15 +302,17 @@ func Part2(content string) (int, error) {
 		return 0, fmt.Errorf("update parsing: %w", err)
 	}
 
-	for _, update := range updates {
-		if InOrder(rules, update) {
+	for i, update := range updates {
+		if rules.InOrder(update) {
 			continue
 		}
 
-		ordered := Order(rules, update)
+		ordered, err := rules.Sort(update)
+		if err != nil {
+			return 0, fmt.Errorf("update %d: %w", i+1, err)
+		}
 
 		total += ordered[len(update)/2]
-
 	}
 
 	return total, nil
@@ -1,11 +1,21 @@
 package main
 
 import (
+	"errors"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/max-nicholson/advent-of-code-2024/lib/testutil"
 )
 
-func TestPart1(t *testing.T) {
-	result, err := Part1(`47|53
+func TestExamples(t *testing.T) {
+	testutil.Golden(t, map[string]testutil.Part{
+		"part1": testutil.Content(Part1),
+		"part2": testutil.Content(Part2),
+	})
+}
+
+const exampleRules = `47|53
 97|13
 97|61
 97|47
@@ -25,55 +35,78 @@ func TestPart1(t *testing.T) {
 75|61
 47|29
 75|13
-53|13
-
-75,47,61,53,29
-97,61,53,29,13
-75,29,13
-75,97,47,61,53
-61,13,29
-97,13,75,29,47`)
+53|13`
+
+func TestViolation(t *testing.T) {
+	rules, err := ParseRules(exampleRules)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if result != 143 {
-		t.Fatalf("expected 143, got %d", result)
+
+	cases := []struct {
+		update []int
+		want   Violation
+		ok     bool
+	}{
+		{update: []int{75, 47, 61, 53, 29}},
+		{update: []int{75, 97, 47, 61, 53}, want: Violation{Before: 97, After: 75, BeforeIndex: 1, AfterIndex: 0}, ok: true},
+		{update: []int{61, 13, 29}, want: Violation{Before: 29, After: 13, BeforeIndex: 2, AfterIndex: 1}, ok: true},
+		{update: []int{97, 13, 75, 29, 47}, want: Violation{Before: 75, After: 13, BeforeIndex: 2, AfterIndex: 1}, ok: true},
+	}
+
+	for _, c := range cases {
+		got, ok := rules.Violation(c.update)
+		if ok != c.ok || got != c.want {
+			t.Errorf("Violation(%v) got %v %v, want %v %v", c.update, got, ok, c.want, c.ok)
+		}
 	}
 }
 
-func TestPart2(t *testing.T) {
-	result, err := Part2(`47|53
-97|13
-97|61
-97|47
-75|29
-61|13
-75|53
-29|13
-97|29
-53|29
-61|53
-97|53
-61|29
-47|13
-75|47
-97|75
-47|61
-75|61
-47|29
-75|13
-53|13
-
-75,47,61,53,29
-97,61,53,29,13
-75,29,13
-75,97,47,61,53
-61,13,29
-97,13,75,29,47`)
+func TestSortCycle(t *testing.T) {
+	rules, err := ParseRules("1|2\n2|3\n3|4\n4|2\n5|1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = rules.Sort([]int{5, 4, 3, 2, 1})
+
+	var cycle *CycleError
+	if !errors.As(err, &cycle) {
+		t.Fatalf("Sort() got error %v, want a CycleError", err)
+	}
+
+	if diff := cmp.Diff([]int{2, 3, 4, 2}, cycle.Pages); diff != "" {
+		t.Errorf("CycleError.Pages mismatch (-want +got):\n%s", diff)
+	}
+
+	// the cycle only matters when all of its pages are in the update
+	if _, err := rules.Sort([]int{4, 3, 1}); err != nil {
+		t.Errorf("Sort() without the whole cycle got %v", err)
+	}
+}
+
+func TestOrderings(t *testing.T) {
+	rules, err := ParseRules("1|2\n1|3\n2|4\n3|4")
 	if err != nil {
 		t.Fatal(err)
 	}
-	if result != 123 {
-		t.Fatalf("expected 123, got %d", result)
+
+	var got [][]int
+	for ordering := range rules.Orderings([]int{4, 3, 2, 1}) {
+		got = append(got, ordering)
+	}
+
+	want := [][]int{{1, 3, 2, 4}, {1, 2, 3, 4}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Orderings() mismatch (-want +got):\n%s", diff)
+	}
+
+	count := 0
+	for range rules.Orderings([]int{1, 2, 5, 6}) {
+		count++
+	}
+	// 1 before 2, while 5 and 6 can go anywhere
+	if count != 12 {
+		t.Errorf("Orderings() got %d orderings, want 12", count)
 	}
 }
@@ -1,10 +1,17 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"image/color"
+	"iter"
 	"log"
+	"runtime"
+	"slices"
+	"sync"
 
 	"github.com/max-nicholson/advent-of-code-2024/lib"
+	"github.com/max-nicholson/advent-of-code-2024/lib/render"
 )
 
 type Direction int
@@ -55,7 +62,11 @@ func (d Direction) Rotate() Direction {
 	panic("unreachable")
 }
 
+var renderPath = flag.String("render", "", "Render the guard's patrol to the terminal (-), a .gif or a .png")
+
 func main() {
+	flag.Parse()
+
 	grid, err := lib.ReadLines("pkg/06/input.txt")
 	if err != nil {
 		log.Fatal(err)
@@ -72,6 +83,26 @@ func main() {
 		log.Fatal(err)
 	}
 	fmt.Printf("part2: %d\n", part2)
+
+	if *renderPath != "" {
+		recorder, err := render.Open(*renderPath, palette)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		start, err := FindGuard(grid)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if err := Render(grid, start, recorder); err != nil {
+			log.Fatal(err)
+		}
+
+		if err := recorder.Close(); err != nil {
+			log.Fatal(err)
+		}
+	}
 }
 
 func FindGuard(grid []string) (Point, error) {
@@ -86,42 +117,92 @@ func FindGuard(grid []string) (Point, error) {
 	return Point{}, fmt.Errorf("guard not found")
 }
 
-func UniquePositions(grid []string, start Point) map[Point]struct{} {
-	rows := len(grid)
-	columns := len(grid[0])
+// Patrol yields the guard's position and direction at every step of their
+// route, until they leave the lab
+func Patrol(grid []string, start Point) iter.Seq2[Point, Direction] {
+	return func(yield func(Point, Direction) bool) {
+		rows := len(grid)
+		columns := len(grid[0])
 
-	current := start
-	var direction Direction = North
+		current := start
+		var direction Direction = North
 
-	visited := make(map[Point]struct{})
-	visited[current] = struct{}{}
+		if !yield(current, direction) {
+			return
+		}
 
-	for {
-		delta := direction.Delta()
+		for {
+			delta := direction.Delta()
 
-		x := current.x + delta.x
-		y := current.y + delta.y
+			x := current.x + delta.x
+			y := current.y + delta.y
 
-		if !(0 <= x && x < columns) {
-			break
-		}
+			if !(0 <= x && x < columns) {
+				break
+			}
 
-		if !(0 <= y && y < rows) {
-			break
-		}
+			if !(0 <= y && y < rows) {
+				break
+			}
 
-		if grid[y][x] == '#' {
-			direction = direction.Rotate()
-		} else {
-			current.x = x
-			current.y = y
-			visited[current] = struct{}{}
+			if grid[y][x] == '#' {
+				direction = direction.Rotate()
+			} else {
+				current.x = x
+				current.y = y
+			}
+
+			if !yield(current, direction) {
+				return
+			}
 		}
 	}
+}
+
+func UniquePositions(grid []string, start Point) map[Point]struct{} {
+	visited := make(map[Point]struct{})
+
+	for current := range Patrol(grid, start) {
+		visited[current] = struct{}{}
+	}
 
 	return visited
 }
 
+var palette = render.Palette{
+	Colours: map[rune]color.RGBA{
+		'#': {128, 128, 128, 255},
+		'X': {255, 215, 0, 255},
+		'^': {255, 0, 0, 255},
+		'>': {255, 0, 0, 255},
+		'v': {255, 0, 0, 255},
+		'<': {255, 0, 0, 255},
+	},
+	Default: color.RGBA{32, 32, 32, 255},
+}
+
+// Render draws every step of the guard's patrol, with the positions they've
+// visited marked as X
+func Render(grid []string, start Point, sink render.Sink) error {
+	frame := make(render.Runes, len(grid))
+	for y, line := range grid {
+		frame[y] = []rune(line)
+	}
+
+	previous := start
+	for current, direction := range Patrol(grid, start) {
+		frame[previous.y][previous.x] = 'X'
+		frame[current.y][current.x] = []rune("^>v<")[direction]
+		previous = current
+
+		if err := sink.Frame(frame); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func Part1(grid []string) (int, error) {
 	start, err := FindGuard(grid)
 
@@ -134,64 +215,172 @@ func Part1(grid []string) (int, error) {
 	return len(visited), nil
 }
 
-func Part2(grid []string) (int, error) {
-	total := 0
+// Lab holds a jump table for every cell, so a guard can move from one turn to
+// the next without stepping through each cell in between
+type Lab struct {
+	rows, columns int
+	// jumps[d][i] is the cell where a guard at cell i facing d stops in front
+	// of the next obstacle, or -1 if they leave the lab first
+	jumps [4][]int
+}
 
-	rows := len(grid)
-	columns := len(grid[0])
+func NewLab(grid []string) *Lab {
+	lab := &Lab{rows: len(grid), columns: len(grid[0])}
 
-	start, err := FindGuard(grid)
-	if err != nil {
-		return 0, err
-	}
+	for d := range lab.jumps {
+		direction := Direction(d)
+		delta := direction.Delta()
+		jumps := make([]int, lab.rows*lab.columns)
 
-	visited := UniquePositions(grid, start)
+		for y := range lab.rows {
+			for x := range lab.columns {
+				// start from each cell on the edge the guard would leave by
+				if lab.InBounds(Point{x + delta.x, y + delta.y}) {
+					continue
+				}
 
-	// Obstacle only has a chance of creating an infinite loop if it's on the original guard route
-	// (without any obstacles)
-	for obstacle := range visited {
-		if obstacle == start {
-			// cannot put obstacle at start
-			continue
+				stop := -1
+				for p := (Point{x, y}); lab.InBounds(p); p = (Point{p.x - delta.x, p.y - delta.y}) {
+					if grid[p.y][p.x] == '#' {
+						jumps[lab.index(p)] = -1
+						stop = lab.index(Point{p.x - delta.x, p.y - delta.y})
+					} else {
+						jumps[lab.index(p)] = stop
+					}
+				}
+			}
 		}
 
-		path := make(map[Step]struct{})
-		path[Step{start.x, start.y, North}] = struct{}{}
+		lab.jumps[d] = jumps
+	}
 
-		current := start
-		var direction Direction = North
+	return lab
+}
 
-		for {
-			delta := direction.Delta()
+func (l *Lab) InBounds(p Point) bool {
+	return 0 <= p.x && p.x < l.columns && 0 <= p.y && p.y < l.rows
+}
 
-			x := current.x + delta.x
-			y := current.y + delta.y
+func (l *Lab) index(p Point) int {
+	return p.y*l.columns + p.x
+}
 
-			if !(0 <= x && x < columns) {
-				// Guard left area on x-axis
-				break
-			}
+func (l *Lab) point(i int) Point {
+	return Point{i % l.columns, i / l.columns}
+}
 
-			if !(0 <= y && y < rows) {
-				// Guard left area on y-axis
-				break
+// ahead is how many steps in direction d it takes to get from a to b, or -1
+// if b isn't in front of a
+func ahead(a, b Point, d Direction) int {
+	delta := d.Delta()
+	switch {
+	case delta.x == 0 && a.x == b.x && (b.y-a.y)*delta.y > 0:
+		return (b.y - a.y) * delta.y
+	case delta.y == 0 && a.y == b.y && (b.x-a.x)*delta.x > 0:
+		return (b.x - a.x) * delta.x
+	}
+	return -1
+}
+
+// Loop follows the guard with an extra obstacle in the lab, returning the
+// turns that make up the loop they get stuck in. Each Step is where the guard
+// turns and the direction they leave in
+func (l *Lab) Loop(start, obstacle Point) ([]Step, bool) {
+	current := start
+	direction := North
+
+	seen := make(map[Step]int)
+	var turns []Step
+
+	for {
+		stop := l.jumps[direction][l.index(current)]
+
+		if distance := ahead(current, obstacle, direction); distance != -1 {
+			if stop == -1 || distance <= ahead(current, l.point(stop), direction)+1 {
+				delta := direction.Delta()
+				stop = l.index(Point{obstacle.x - delta.x, obstacle.y - delta.y})
 			}
+		}
 
-			if grid[y][x] == '#' || (x == obstacle.x && y == obstacle.y) {
-				direction = direction.Rotate()
-			} else {
-				step := Step{x, y, direction}
-				if _, ok := path[step]; ok {
-					total += 1
-					// Guard has hit a loop
-					break
+		if stop == -1 {
+			return nil, false
+		}
+
+		current = l.point(stop)
+		direction = direction.Rotate()
+
+		turn := Step{current.x, current.y, direction}
+		if i, ok := seen[turn]; ok {
+			return turns[i:], true
+		}
+		seen[turn] = len(turns)
+		turns = append(turns, turn)
+	}
+}
+
+// Loop is an obstacle that traps the guard, and the turns they make forever
+type Loop struct {
+	Obstacle Point
+	Path     []Step
+}
+
+// FindLoops tries an obstacle on every cell of the guard's original route,
+// spread across workers. Loops are returned top to bottom, left to right
+func FindLoops(grid []string, start Point, workers int) []Loop {
+	lab := NewLab(grid)
+
+	var candidates []Point
+	for y := range lab.rows {
+		for x := range lab.columns {
+			candidates = append(candidates, Point{x, y})
+		}
+	}
+
+	visited := UniquePositions(grid, start)
+	candidates = slices.DeleteFunc(candidates, func(p Point) bool {
+		_, ok := visited[p]
+		// cannot put obstacle at start
+		return !ok || p == start
+	})
+
+	paths := make([][]Step, len(candidates))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for range max(workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if path, ok := lab.Loop(start, candidates[i]); ok {
+					paths[i] = path
 				}
-				current.x = x
-				current.y = y
-				path[step] = struct{}{}
 			}
+		}()
+	}
+
+	for i := range candidates {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var loops []Loop
+	for i, path := range paths {
+		if path != nil {
+			loops = append(loops, Loop{Obstacle: candidates[i], Path: path})
 		}
 	}
+	return loops
+}
 
-	return total, nil
+func Part2(grid []string) (int, error) {
+	start, err := FindGuard(grid)
+	if err != nil {
+		return 0, err
+	}
+
+	// Obstacle only has a chance of creating an infinite loop if it's on the original guard route
+	// (without any obstacles)
+	return len(FindLoops(grid, start, runtime.GOMAXPROCS(0))), nil
 }
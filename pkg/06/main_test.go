@@ -1,44 +1,101 @@
 package main
 
 import (
+	"slices"
 	"strings"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/max-nicholson/advent-of-code-2024/lib/gen"
+	"github.com/max-nicholson/advent-of-code-2024/lib/testutil"
 )
 
-func TestPart1(t *testing.T) {
-	result, err := Part1(strings.Split(`....#.....
-.........#
-..........
-..#.......
-.......#..
-..........
-.#..^.....
-........#.
-#.........
-......#...`, "\n"))
-	if err != nil {
-		t.Fatal(err)
+func TestExamples(t *testing.T) {
+	testutil.Golden(t, map[string]testutil.Part{
+		"part1": testutil.Lines(Part1),
+		"part2": testutil.Lines(Part2),
+	})
+}
+
+// loops steps the guard one cell at a time with each obstacle added
+func loops(grid []string, start Point) []Point {
+	var obstacles []Point
+	for y, line := range grid {
+		for x := range line {
+			obstacle := Point{x, y}
+			if grid[y][x] == '#' || obstacle == start {
+				continue
+			}
+
+			blocked := slices.Clone(grid)
+			blocked[y] = line[:x] + "#" + line[x+1:]
+
+			seen := make(map[Step]struct{})
+			for current, direction := range Patrol(blocked, start) {
+				step := Step{current.x, current.y, direction}
+				if _, ok := seen[step]; ok {
+					obstacles = append(obstacles, obstacle)
+					break
+				}
+				seen[step] = struct{}{}
+			}
+		}
 	}
-	if result != 41 {
-		t.Fatalf("expected 41, got %d", result)
+	return obstacles
+}
+
+func TestFindLoopsMatchesStepping(t *testing.T) {
+	for seed := range uint64(5) {
+		input, err := gen.Generate(6, 30, seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		grid := strings.Split(input, "\n")
+
+		start, err := FindGuard(grid)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []Point
+		for _, loop := range FindLoops(grid, start, 4) {
+			got = append(got, loop.Obstacle)
+		}
+
+		if diff := cmp.Diff(loops(grid, start), got, cmp.AllowUnexported(Point{})); diff != "" {
+			t.Errorf("seed %d: FindLoops() mismatch (-want +got):\n%s", seed, diff)
+		}
 	}
 }
 
-func TestPart2(t *testing.T) {
-	result, err := Part2(strings.Split(`....#.....
-.........#
-..........
-..#.......
-.......#..
-..........
-.#..^.....
-........#.
-#.........
-......#...`, "\n"))
-	if err != nil {
-		t.Fatal(err)
+func TestLoopPath(t *testing.T) {
+	grid := []string{
+		".#...",
+		"....#",
+		".....",
+		"...#.",
+		".^...",
+	}
+
+	lab := NewLab(grid)
+
+	path, ok := lab.Loop(Point{1, 4}, Point{2, 4})
+	if ok {
+		t.Fatalf("Loop() got unexpected loop %v", path)
+	}
+
+	path, ok = lab.Loop(Point{1, 4}, Point{0, 2})
+	if !ok {
+		t.Fatal("Loop() want a loop")
+	}
+
+	want := []Step{
+		{1, 1, East},
+		{3, 1, South},
+		{3, 2, West},
+		{1, 2, North},
 	}
-	if result != 6 {
-		t.Fatalf("expected 6, got %d", result)
+	if diff := cmp.Diff(want, path, cmp.AllowUnexported(Step{})); diff != "" {
+		t.Errorf("Loop() mismatch (-want +got):\n%s", diff)
 	}
 }
@@ -2,7 +2,9 @@ package main
 
 import (
 	"fmt"
+	"iter"
 	"log"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -33,70 +35,153 @@ type Equation struct {
 	value   int
 }
 
-type Operator int
+// Operator combines the running total with the next number. Solving works
+// right to left, so each operator also needs its inverse
+type Operator interface {
+	Symbol() string
+	Apply(a, b int) int
+	// Undo finds the a where Apply(a, b) == result, if there's a positive one
+	Undo(result, b int) (int, bool)
+}
 
-const (
-	Add Operator = iota + 1
-	Multiply
-	Concatenate
-)
+type add struct{}
 
-type State struct {
-	index    int
-	value    int
-	operator Operator
+func (add) Symbol() string     { return "+" }
+func (add) Apply(a, b int) int { return a + b }
+func (add) Undo(result, b int) (int, bool) {
+	return result - b, result > b
 }
 
-func (e Equation) SolveableWith(operators []Operator) bool {
-	if len(e.numbers) == 1 {
-		return e.numbers[0] == e.value
-	}
+type multiply struct{}
 
-	stack := []State{}
-	for _, op := range operators {
-		stack = append(stack, State{index: 1, value: e.numbers[0], operator: op})
+func (multiply) Symbol() string     { return "*" }
+func (multiply) Apply(a, b int) int { return a * b }
+func (multiply) Undo(result, b int) (int, bool) {
+	return result / b, result%b == 0 && result > 0
+}
+
+type concatenate struct{}
+
+func (concatenate) Symbol() string { return "||" }
+func (concatenate) Apply(a, b int) int {
+	return a*shift(b) + b
+}
+func (concatenate) Undo(result, b int) (int, bool) {
+	s := shift(b)
+	return result / s, result%s == b && result/s > 0
+}
+
+// shift is the power of 10 that moves a number left past b's digits
+func shift(b int) int {
+	s := 10
+	for b >= s {
+		s *= 10
 	}
+	return s
+}
 
-	for len(stack) > 0 {
-		l := len(stack) - 1
-		s := stack[l]
-		stack = stack[:l]
-		var value = s.value
-
-		switch s.operator {
-		case Add:
-			value += e.numbers[s.index]
-		case Multiply:
-			value *= e.numbers[s.index]
-		case Concatenate:
-			v := strconv.Itoa(value)
-			v += strconv.Itoa(e.numbers[s.index])
-			value, _ = strconv.Atoi(v)
-		}
+var (
+	Add         Operator = add{}
+	Multiply    Operator = multiply{}
+	Concatenate Operator = concatenate{}
+)
 
-		if s.index == len(e.numbers)-1 {
-			if value == e.value {
+// Solutions yields every sequence of operators, left to right, that makes the
+// numbers equal the test value. Numbers must be positive, so any operator
+// that can't be undone to a positive total prunes that branch
+func (e Equation) Solutions(operators []Operator) iter.Seq[[]Operator] {
+	return func(yield func([]Operator) bool) {
+		sequence := make([]Operator, len(e.numbers)-1)
+
+		var solve func(i, target int) bool
+		solve = func(i, target int) bool {
+			if i == 0 {
+				if target == e.numbers[0] {
+					return yield(slices.Clone(sequence))
+				}
 				return true
 			}
 
-			// Try another combination
-			continue
+			for _, op := range operators {
+				a, ok := op.Undo(target, e.numbers[i])
+				if !ok {
+					continue
+				}
+
+				sequence[i-1] = op
+				if !solve(i-1, a) {
+					return false
+				}
+			}
+			return true
 		}
 
-		if value > e.value {
-			// We've already gone past the target
-			// Since our only operations are additive, it's now impossible to reach the target
-			continue
+		solve(len(e.numbers)-1, e.value)
+	}
+}
+
+// Count is the number of operator sequences that solve the equation, without
+// building each one
+func (e Equation) Count(operators []Operator) int {
+	type key struct{ i, target int }
+	cache := make(map[key]int)
+
+	var count func(i, target int) int
+	count = func(i, target int) int {
+		if i == 0 {
+			if target == e.numbers[0] {
+				return 1
+			}
+			return 0
+		}
+
+		k := key{i, target}
+		if n, ok := cache[k]; ok {
+			return n
 		}
 
+		n := 0
 		for _, op := range operators {
-			stack = append(stack, State{index: s.index + 1, value: value, operator: op})
+			if a, ok := op.Undo(target, e.numbers[i]); ok {
+				n += count(i-1, a)
+			}
 		}
+		cache[k] = n
+		return n
 	}
 
+	return count(len(e.numbers)-1, e.value)
+}
+
+func (e Equation) SolveableWith(operators []Operator) bool {
+	for range e.Solutions(operators) {
+		return true
+	}
 	return false
 }
 
+// Evaluate applies the operators left to right
+func (e Equation) Evaluate(operators []Operator) int {
+	value := e.numbers[0]
+	for i, op := range operators {
+		value = op.Apply(value, e.numbers[i+1])
+	}
+	return value
+}
+
+// Format writes out an equation solved with the operators, e.g.
+// 3267 = 81 + 40 * 27
+func (e Equation) Format(operators []Operator) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d = %d", e.value, e.numbers[0])
+	for i, op := range operators {
+		fmt.Fprintf(&b, " %s %d", op.Symbol(), e.numbers[i+1])
+	}
+	return b.String()
+}
+
+// ParseEquations reads "value: numbers..." lines. Numbers below 1 are rejected,
+// as Solutions relies on every total to the left being positive to prune
 func ParseEquations(lines []string) ([]Equation, error) {
 	equations := make([]Equation, len(lines))
 	for i, equation := range lines {
@@ -115,6 +200,9 @@ func ParseEquations(lines []string) ([]Equation, error) {
 			if err != nil {
 				return nil, fmt.Errorf("line %d equation %s number %s: %w", i+1, equation, n, err)
 			}
+			if v < 1 {
+				return nil, fmt.Errorf("line %d equation %s number %s: must be positive", i+1, equation, n)
+			}
 			numbers[j] = v
 		}
 
@@ -3,40 +3,100 @@ package main
 import (
 	"strings"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/max-nicholson/advent-of-code-2024/lib/gen"
+	"github.com/max-nicholson/advent-of-code-2024/lib/testutil"
 )
 
-func TestPart1(t *testing.T) {
-	result, err := Part1(strings.Split(`190: 10 19
-3267: 81 40 27
-83: 17 5
-156: 15 6
-7290: 6 8 6 15
-161011: 16 10 13
-192: 17 8 14
-21037: 9 7 18 13
-292: 11 6 16 20`, "\n"))
+func TestExamples(t *testing.T) {
+	testutil.Golden(t, map[string]testutil.Part{
+		"part1": testutil.Lines(Part1),
+		"part2": testutil.Lines(Part2),
+	})
+}
+
+func TestSolutions(t *testing.T) {
+	equations, err := ParseEquations([]string{"3267: 81 40 27", "7290: 6 8 6 15", "83: 17 5", "4: 2 2"})
 	if err != nil {
 		t.Fatal(err)
 	}
-	if result != 3749 {
-		t.Fatalf("expected 3749, got %d", result)
+
+	operators := []Operator{Add, Multiply, Concatenate}
+	want := [][]string{
+		{"3267 = 81 * 40 + 27", "3267 = 81 + 40 * 27"},
+		{"7290 = 6 * 8 || 6 * 15"},
+		nil,
+		{"4 = 2 + 2", "4 = 2 * 2"},
+	}
+
+	for i, equation := range equations {
+		var got []string
+		for solution := range equation.Solutions(operators) {
+			if value := equation.Evaluate(solution); value != equation.value {
+				t.Errorf("%s evaluates to %d", equation.Format(solution), value)
+			}
+			got = append(got, equation.Format(solution))
+		}
+
+		if diff := cmp.Diff(want[i], got); diff != "" {
+			t.Errorf("Solutions() mismatch (-want +got):\n%s", diff)
+		}
+
+		if count := equation.Count(operators); count != len(want[i]) {
+			t.Errorf("Count() got %d, want %d", count, len(want[i]))
+		}
+	}
+}
+
+func TestParseEquationsRejectsNonPositive(t *testing.T) {
+	for _, line := range []string{"0: 0", "5: 5 0", "6: 0 3 2", "1: 3 -2"} {
+		if _, err := ParseEquations([]string{line}); err == nil || !strings.Contains(err.Error(), "must be positive") {
+			t.Errorf("ParseEquations(%q) got %v, want an error for a number below 1", line, err)
+		}
+	}
+}
+
+type subtract struct{}
+
+func (subtract) Symbol() string     { return "-" }
+func (subtract) Apply(a, b int) int { return a - b }
+func (subtract) Undo(result, b int) (int, bool) {
+	return result + b, result+b > 0
+}
+
+func TestCustomOperator(t *testing.T) {
+	equation := Equation{numbers: []int{10, 3, 2}, value: 5}
+
+	if equation.SolveableWith([]Operator{Add, Multiply}) {
+		t.Error("want no solution with add and multiply")
+	}
+
+	if count := equation.Count([]Operator{Add, Multiply, subtract{}}); count != 1 {
+		t.Errorf("Count() got %d, want 1", count)
 	}
 }
 
-func TestPart2(t *testing.T) {
-	result, err := Part2(strings.Split(`190: 10 19
-3267: 81 40 27
-83: 17 5
-156: 15 6
-7290: 6 8 6 15
-161011: 16 10 13
-192: 17 8 14
-21037: 9 7 18 13
-292: 11 6 16 20`, "\n"))
+func TestCountMatchesSolutions(t *testing.T) {
+	input, err := gen.Generate(7, 200, 1)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if result != 11387 {
-		t.Fatalf("expected 11387, got %d", result)
+
+	equations, err := ParseEquations(strings.Split(input, "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	operators := []Operator{Add, Multiply, Concatenate}
+	for _, equation := range equations {
+		n := 0
+		for range equation.Solutions(operators) {
+			n++
+		}
+
+		if count := equation.Count(operators); count != n {
+			t.Errorf("%v: Count() got %d, Solutions() yielded %d", equation, count, n)
+		}
 	}
 }
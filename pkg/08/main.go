@@ -2,7 +2,9 @@ package main
 
 import (
 	"fmt"
+	"iter"
 	"log"
+	"math"
 
 	"github.com/max-nicholson/advent-of-code-2024/lib"
 	"github.com/mowshon/iterium"
@@ -75,69 +77,165 @@ func ParseAntennae(lines []string) map[rune][]Point {
 	return antennae
 }
 
-func UniqueAntinodes(lines []string) map[Point]struct{} {
-	antennae := ParseAntennae(lines)
+// Coordinate is a point in up to 3 dimensions, with Z left at 0 on a grid
+type Coordinate struct {
+	X, Y, Z int
+}
 
-	uniqueAntinodes := make(map[Point]struct{})
+func (c Coordinate) Add(other Coordinate) Coordinate {
+	return Coordinate{c.X + other.X, c.Y + other.Y, c.Z + other.Z}
+}
 
-	rows := len(lines)
-	columns := len(lines[0])
+func (c Coordinate) Sub(other Coordinate) Coordinate {
+	return Coordinate{c.X - other.X, c.Y - other.Y, c.Z - other.Z}
+}
 
-	for _, antenna := range antennae {
-		if len(antenna) == 1 {
+func (c Coordinate) Scale(k int) Coordinate {
+	return Coordinate{c.X * k, c.Y * k, c.Z * k}
+}
+
+// Reduce splits c into the smallest step in the same direction, and how many
+// of those steps make up c. The zero coordinate has no direction, so it's 0
+// steps of itself
+func (c Coordinate) Reduce() (Coordinate, int) {
+	n := lib.GCD(lib.GCD(c.X, c.Y), c.Z)
+	if n == 0 {
+		return c, 0
+	}
+	return Coordinate{c.X / n, c.Y / n, c.Z / n}, n
+}
+
+// Window is the inclusive box of space to look for antinodes in
+type Window struct {
+	Min, Max Coordinate
+}
+
+func (w Window) Contains(c Coordinate) bool {
+	return w.Min.X <= c.X && c.X <= w.Max.X &&
+		w.Min.Y <= c.Y && c.Y <= w.Max.Y &&
+		w.Min.Z <= c.Z && c.Z <= w.Max.Z
+}
+
+// steps finds the range of k where start + k*step is inside the window
+func (w Window) steps(start, step Coordinate) (int, int, bool) {
+	lo, hi := math.MinInt, math.MaxInt
+	axes := [][4]int{
+		{start.X, step.X, w.Min.X, w.Max.X},
+		{start.Y, step.Y, w.Min.Y, w.Max.Y},
+		{start.Z, step.Z, w.Min.Z, w.Max.Z},
+	}
+
+	for _, axis := range axes {
+		from, by, low, high := axis[0], axis[1], axis[2], axis[3]
+		if by == 0 {
+			if from < low || from > high {
+				return 0, 0, false
+			}
 			continue
 		}
 
-		combinations := iterium.Combinations(antenna, 2)
-		for pair := range combinations.Chan() {
-			a := pair[0]
-			b := pair[1]
+		a, b := divide(low-from, by), divide(high-from, by)
+		if by < 0 {
+			a, b = b, a
+		}
+		lo = lib.Max(lo, ceil(a))
+		hi = lib.Min(hi, floor(b))
+	}
 
-			dy := lib.Abs(a.y - b.y)
-			dx := lib.Abs(a.x - b.x)
+	return lo, hi, lo <= hi
+}
 
-			var first Point
-			var second Point
-			if a.x < b.x {
-				first = Point{
-					a.x - dx,
-					a.y - dy,
-				}
-				second = Point{
-					b.x + dx,
-					b.y + dy,
-				}
-			} else {
-				first = Point{
-					a.x + dx,
-					a.y - dy,
-				}
-				second = Point{
-					b.x - dx,
-					b.y + dy,
-				}
+// fraction is n/d kept exact, with d positive
+type fraction struct{ n, d int }
+
+func divide(n, d int) fraction {
+	if d < 0 {
+		return fraction{-n, -d}
+	}
+	return fraction{n, d}
+}
+
+func floor(f fraction) int {
+	q := f.n / f.d
+	if f.n%f.d != 0 && f.n < 0 {
+		q--
+	}
+	return q
+}
+
+func ceil(f fraction) int {
+	return -floor(fraction{-f.n, f.d})
+}
+
+// Harmonics picks the antinodes in line with a pair of antennas. Positions
+// are counted in steps from the first antenna, with the second n steps away
+type Harmonics interface {
+	Steps(n, lo, hi int) iter.Seq[int]
+}
+
+// Multiples puts antinodes at whole multiples of the distance between the
+// antennas, so Multiples{-1, 2} is one on either side
+type Multiples []int
+
+func (m Multiples) Steps(n, lo, hi int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for _, multiple := range m {
+			if k := multiple * n; lo <= k && k <= hi && !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+// Ratio puts antinodes wherever one antenna is Ratio times as far away as the
+// other, which includes any whole points between them
+type Ratio int
+
+func (r Ratio) Steps(n, lo, hi int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		ratio := int(r)
+		// |k| = r|k-n| or r|k| = |k-n|
+		candidates := []fraction{
+			divide(ratio*n, ratio-1), divide(ratio*n, ratio+1),
+			divide(-n, ratio-1), divide(n, ratio+1),
+		}
+
+		seen := make(map[int]struct{})
+		for _, c := range candidates {
+			if c.d == 0 || c.n%c.d != 0 {
+				continue
 			}
 
-			if 0 <= first.x && first.x < columns && 0 <= first.y && first.y < rows {
-				uniqueAntinodes[first] = struct{}{}
+			k := c.n / c.d
+			if _, ok := seen[k]; ok || k < lo || k > hi {
+				continue
 			}
+			seen[k] = struct{}{}
 
-			if 0 <= second.x && second.x < columns && 0 <= second.y && second.y < rows {
-				uniqueAntinodes[second] = struct{}{}
+			if !yield(k) {
+				return
 			}
 		}
 	}
-
-	return uniqueAntinodes
 }
 
-func UniqueAntinodesWithResonance(lines []string) map[Point]struct{} {
-	antennae := ParseAntennae(lines)
+// Resonance puts an antinode on every point in line with the antennas
+type Resonance struct{}
 
-	uniqueAntinodes := make(map[Point]struct{})
+func (Resonance) Steps(n, lo, hi int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for k := lo; k <= hi; k++ {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
 
-	rows := len(lines)
-	columns := len(lines[0])
+// Antinodes finds every antinode inside the window for each pair of antennas
+// with the same frequency
+func Antinodes(antennae map[rune][]Coordinate, harmonics Harmonics, window Window) map[Coordinate]struct{} {
+	antinodes := make(map[Coordinate]struct{})
 
 	for _, antenna := range antennae {
 		if len(antenna) == 1 {
@@ -147,42 +245,52 @@ func UniqueAntinodesWithResonance(lines []string) map[Point]struct{} {
 		combinations := iterium.Combinations(antenna, 2)
 		for pair := range combinations.Chan() {
 			a := pair[0]
-			b := pair[1]
-
-			uniqueAntinodes[a] = struct{}{}
-			uniqueAntinodes[b] = struct{}{}
-
-			dy := lib.Abs(a.y - b.y)
-			dx := b.x - a.x
+			step, n := pair[1].Sub(a).Reduce()
+			if n == 0 {
+				// antennas in the same place aren't in line with anything
+				// in particular
+				continue
+			}
 
-			var next Point
-			// Up
-			next = a
-			for {
-				next = Point{next.x - dx, next.y - dy}
-				if next.x < 0 || next.x >= columns || next.y < 0 || next.y >= rows {
-					break
-				}
+			lo, hi, ok := window.steps(a, step)
+			if !ok {
+				continue
+			}
 
-				uniqueAntinodes[next] = struct{}{}
+			for k := range harmonics.Steps(n, lo, hi) {
+				antinodes[a.Add(step.Scale(k))] = struct{}{}
 			}
+		}
+	}
 
-			// Down
-			next = b
-			for {
-				next = Point{next.x + dx, next.y + dy}
-				if next.x < 0 || next.x >= columns || next.y < 0 || next.y >= rows {
-					break
-				}
+	return antinodes
+}
 
-				uniqueAntinodes[next] = struct{}{}
-			}
+func gridAntinodes(lines []string, harmonics Harmonics) map[Point]struct{} {
+	antennae := make(map[rune][]Coordinate)
+	for frequency, points := range ParseAntennae(lines) {
+		for _, p := range points {
+			antennae[frequency] = append(antennae[frequency], Coordinate{X: p.x, Y: p.y})
 		}
 	}
 
+	window := Window{Max: Coordinate{X: len(lines[0]) - 1, Y: len(lines) - 1}}
+
+	uniqueAntinodes := make(map[Point]struct{})
+	for c := range Antinodes(antennae, harmonics, window) {
+		uniqueAntinodes[Point{c.X, c.Y}] = struct{}{}
+	}
 	return uniqueAntinodes
 }
 
+func UniqueAntinodes(lines []string) map[Point]struct{} {
+	return gridAntinodes(lines, Multiples{-1, 2})
+}
+
+func UniqueAntinodesWithResonance(lines []string) map[Point]struct{} {
+	return gridAntinodes(lines, Resonance{})
+}
+
 func Part1(lines []string) (int, error) {
 	uniqueAntinodes := UniqueAntinodes(lines)
 
@@ -4,27 +4,18 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/max-nicholson/advent-of-code-2024/lib/gen"
+	"github.com/max-nicholson/advent-of-code-2024/lib/testutil"
 )
 
-func TestPart1(t *testing.T) {
-	result, err := Part1(strings.Split(`............
-........0...
-.....0......
-.......0....
-....0.......
-......A.....
-............
-............
-........A...
-.........A..
-............
-............`, "\n"))
-	if err != nil {
-		t.Fatal(err)
-	}
-	if result != 14 {
-		t.Fatalf("expected 14, got %d", result)
-	}
+func TestExamples(t *testing.T) {
+	testutil.Golden(t, map[string]testutil.Part{
+		"part1": testutil.Lines(Part1),
+		"part2": testutil.Lines(Part2),
+	})
 }
 
 func TestUniqueAntinodes(t *testing.T) {
@@ -74,23 +65,113 @@ func TestUniqueAntinodes2(t *testing.T) {
 	}
 }
 
-func TestPart2(t *testing.T) {
-	result, err := Part2(strings.Split(`............
-........0...
-.....0......
-.......0....
-....0.......
-......A.....
-............
-............
-........A...
-.........A..
-............
-............`, "\n"))
+func TestResonanceMatchesCollinear(t *testing.T) {
+	input, err := gen.Generate(8, 40, 1)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if result != 34 {
-		t.Fatalf("expected 34, got %d", result)
+	lines := strings.Split(input, "\n")
+	antennae := ParseAntennae(lines)
+
+	want := make(map[Point]struct{})
+	for y := range len(lines) {
+		for x := range len(lines[0]) {
+			for _, antenna := range antennae {
+				for i, a := range antenna {
+					for _, b := range antenna[i+1:] {
+						if (b.x-a.x)*(y-a.y) == (b.y-a.y)*(x-a.x) {
+							want[Point{x, y}] = struct{}{}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	if diff := cmp.Diff(want, UniqueAntinodesWithResonance(lines), cmp.AllowUnexported(Point{})); diff != "" {
+		t.Errorf("UniqueAntinodesWithResonance() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAntinodes(t *testing.T) {
+	antennae := map[rune][]Coordinate{
+		'a': {{0, 0, 0}, {3, 3, 3}},
+	}
+
+	cases := []struct {
+		name      string
+		harmonics Harmonics
+		window    Window
+		want      []Coordinate
+	}{
+		{
+			name:      "multiples in 3d",
+			harmonics: Multiples{-1, 2},
+			window:    Window{Min: Coordinate{-10, -10, -10}, Max: Coordinate{10, 10, 10}},
+			want:      []Coordinate{{-3, -3, -3}, {6, 6, 6}},
+		},
+		{
+			name:      "twice as far includes points between",
+			harmonics: Ratio(2),
+			window:    Window{Min: Coordinate{-10, -10, -10}, Max: Coordinate{10, 10, 10}},
+			want:      []Coordinate{{-3, -3, -3}, {1, 1, 1}, {2, 2, 2}, {6, 6, 6}},
+		},
+		{
+			name:      "resonance in a window away from the origin",
+			harmonics: Resonance{},
+			window:    Window{Min: Coordinate{-1000001, -1000003, -1000003}, Max: Coordinate{-999999, 0, 0}},
+			want:      []Coordinate{{-1000001, -1000001, -1000001}, {-1000000, -1000000, -1000000}, {-999999, -999999, -999999}},
+		},
+		{
+			name:      "flat window",
+			harmonics: Resonance{},
+			window:    Window{Min: Coordinate{-5, -5, 2}, Max: Coordinate{5, 5, 2}},
+			want:      []Coordinate{{2, 2, 2}},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var got []Coordinate
+			for coordinate := range Antinodes(antennae, c.harmonics, c.window) {
+				got = append(got, coordinate)
+			}
+
+			sortCoordinates := cmpopts.SortSlices(func(a, b Coordinate) bool { return a.X < b.X })
+			if diff := cmp.Diff(c.want, got, sortCoordinates); diff != "" {
+				t.Errorf("Antinodes() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAntinodesCoincident(t *testing.T) {
+	// the pair in the same place is skipped, leaving each copy paired with
+	// the antenna at 3,3,3
+	antennae := map[rune][]Coordinate{
+		'a': {{1, 1, 1}, {1, 1, 1}, {3, 3, 3}},
+	}
+	window := Window{Min: Coordinate{-10, -10, -10}, Max: Coordinate{10, 10, 10}}
+
+	cases := []struct {
+		name      string
+		harmonics Harmonics
+		want      int
+	}{
+		{"multiples", Multiples{-1, 2}, 2},
+		{"ratio", Ratio(2), 2},
+		{"resonance", Resonance{}, 21},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := len(Antinodes(antennae, c.harmonics, window)); got != c.want {
+				t.Errorf("Antinodes() got %d antinodes, want %d", got, c.want)
+			}
+		})
+	}
+
+	if step, n := (Coordinate{}).Reduce(); step != (Coordinate{}) || n != 0 {
+		t.Errorf("Reduce() got %v, %d, want the zero coordinate and 0", step, n)
 	}
 }
@@ -1,19 +1,14 @@
 package main
 
 import (
+	"container/heap"
 	"fmt"
 	"log"
+	"slices"
 
 	"github.com/max-nicholson/advent-of-code-2024/lib"
 )
 
-type Mode int
-
-const (
-	File Mode = iota + 1
-	FreeSpace
-)
-
 func main() {
 	lines, err := lib.ReadLines("pkg/09/input.txt")
 	if err != nil {
@@ -37,203 +32,297 @@ func ParseLength(b byte) int {
 	return int(rune(b) - '0')
 }
 
-func Part1(lines []string) (int, error) {
-	diskMap := lines[0]
-	length := len(diskMap)
-
-	type State struct {
-		index  int
-		fileId int
-		mode   Mode
-		blocks int
-	}
-
-	left := State{index: 0, fileId: -1, mode: File}
-	var right State
-	if length%2 == 0 {
-		// Last entry is free space, can be ignored
-		right = State{index: length - 2, fileId: length / 2}
-	} else {
-		right = State{index: length - 1, fileId: length/2 + 1}
-	}
+// Extent is a run of Length blocks starting at block Start
+type Extent struct {
+	Start  int
+	Length int
+}
 
-	var checksum = 0
-	var position = 0
+// Checksum is the sum of position * id over every block in the extent
+func (e Extent) Checksum(id int) int {
+	return id * (e.Length*e.Start + e.Length*(e.Length-1)/2)
+}
 
-	for left.index < right.index {
-		if left.mode == File {
-			if left.blocks > 0 {
-				panic("partially consumed block in left File mode")
-			}
+// Move is a step of compaction, moving Length blocks of file ID from block
+// From to block To
+type Move struct {
+	ID     int
+	From   int
+	To     int
+	Length int
+}
 
-			left.fileId += 1
-			blocks := ParseLength(diskMap[left.index])
+func (m Move) String() string {
+	return fmt.Sprintf("file %d: %d blocks from %d to %d", m.ID, m.Length, m.From, m.To)
+}
 
-			for range blocks {
-				checksum += left.fileId * position
-				// fmt.Printf("%d * %d = %d\n", position, left.fileId, left.fileId*position)
-				position += 1
-			}
+type Strategy int
 
-			left.mode = FreeSpace
-			left.index += 1
-		} else {
-			var space int
-			if left.blocks > 0 {
-				// partially used free space
-				space = left.blocks
-			} else {
-				space = ParseLength(diskMap[left.index])
-			}
+const (
+	// Blocks moves one block at a time, splitting files across gaps
+	Blocks Strategy = iota + 1
+	// Files moves each file whole into the leftmost gap big enough for it
+	Files
+)
 
-			var blocks int
-			if right.blocks > 0 {
-				// partially used file
-				blocks = right.blocks
-			} else {
-				// new complete file block
-				right.fileId -= 1
-				blocks = ParseLength(diskMap[right.index])
-			}
+// Disk stores each file as a list of extents rather than block by block, so
+// the cost depends on the length of the disk map, not the size of the disk
+type Disk struct {
+	// files[id] are the extents holding file id
+	files [][]Extent
+	// free is each gap between files, left to right
+	free []Extent
+}
 
-			for range lib.Min(blocks, space) {
-				// fmt.Printf("%d * %d = %d\n", position, right.fileId, right.fileId*position)
-				checksum += right.fileId * position
-				position += 1
-			}
+func ParseDisk(diskMap string) (*Disk, error) {
+	disk := &Disk{
+		files: make([][]Extent, 0, len(diskMap)/2+1),
+		free:  make([]Extent, 0, len(diskMap)/2),
+	}
 
-			if blocks > space {
-				// FreeSpace on left is fully consumed
-				left.mode = File
-				left.index += 1
-				left.blocks = 0
-
-				// Must have leftover file blocks on the right
-				right.blocks = blocks - space
-			} else if blocks < space {
-				// File on right is fully consumed
-				right.index -= 2
-				right.blocks = 0
-
-				// We must have leftover space on the left
-				left.blocks = space - blocks
-			} else {
-				// Both left and right are fully consumed
-				left.mode = File
-				left.index += 1
-				left.blocks = 0
-
-				right.index -= 2
-				right.blocks = 0
-			}
+	position := 0
+	for i := range len(diskMap) {
+		if diskMap[i] < '0' || diskMap[i] > '9' {
+			return nil, fmt.Errorf("invalid length %q at %d", diskMap[i], i)
 		}
-	}
 
-	if right.blocks > 0 {
-		// left caught up to right with some spare
-		for range right.blocks {
-			// fmt.Printf("%d * %d = %d\n", position, right.fileId, right.fileId*position)
-			checksum += position * right.fileId
-			position += 1
+		extent := Extent{Start: position, Length: ParseLength(diskMap[i])}
+		if i%2 == 0 {
+			disk.files = append(disk.files, []Extent{extent})
+		} else {
+			disk.free = append(disk.free, extent)
 		}
-		right.blocks = 0
+		position += extent.Length
 	}
 
-	return checksum, nil
+	return disk, nil
 }
 
-func Part2(lines []string) (int, error) {
-	diskMap := lines[0]
-	length := len(diskMap)
+// Compact moves files from the end of the disk into free space at the start.
+// Every move is passed to trace, which can be nil
+func (d *Disk) Compact(strategy Strategy, trace func(Move)) {
+	if trace == nil {
+		trace = func(Move) {}
+	}
 
-	disk := make([]int, 0, length*4)
-	var fileId = -1
-	for i := 0; i < length; i++ {
-		blocks := ParseLength(diskMap[i])
-		if i%2 == 0 {
-			fileId += 1
-			for range blocks {
-				disk = append(disk, fileId)
+	switch strategy {
+	case Blocks:
+		d.compactBlocks(trace)
+	case Files:
+		d.compactFiles(trace)
+	}
+}
+
+// compactBlocks fills gaps left to right with the last blocks on the disk.
+// Blocks only move left, so once the next gap is after the file being moved
+// the disk is compact
+func (d *Disk) compactBlocks(trace func(Move)) {
+	gap := 0
+	for id := len(d.files) - 1; id >= 0; id-- {
+		file := d.files[id][0]
+		var moved []Extent
+
+		for file.Length > 0 {
+			for gap < len(d.free) && d.free[gap].Length == 0 {
+				gap++
 			}
-		} else {
-			for range blocks {
-				disk = append(disk, -1)
+			if gap == len(d.free) || d.free[gap].Start > file.Start {
+				break
 			}
+
+			// take from the end of the file
+			n := lib.Min(d.free[gap].Length, file.Length)
+			file.Length -= n
+			to := Extent{Start: d.free[gap].Start, Length: n}
+			moved = append(moved, to)
+			trace(Move{ID: id, From: file.Start + file.Length, To: to.Start, Length: n})
+
+			d.free[gap].Start += n
+			d.free[gap].Length -= n
+		}
+
+		if file.Length > 0 {
+			moved = append(moved, file)
+		}
+		d.files[id] = moved
+
+		if gap == len(d.free) || d.free[gap].Start > file.Start {
+			return
 		}
 	}
+}
 
-	var min = 0
-	var minFileId = fileId
-	positions := len(disk) - 1
-	var i = positions
-	for i >= 0 {
-		fileId := disk[i]
+// gaps is a min-heap of the start of each gap with the same length
+type gaps = lib.PriorityQueue[int]
+
+// compactFiles keeps a min-heap of gaps for each length, so the leftmost gap
+// a file fits in is the smallest start across the heaps at least as long as
+// it. The gap a file leaves behind is after every file still to move, so it
+// never needs adding back
+func (d *Disk) compactFiles(trace func(Move)) {
+	var free [10]gaps
+	for _, gap := range d.free {
+		if gap.Length > 0 {
+			heap.Push(&free[gap.Length], &lib.PriorityQueueItem[int]{Value: gap.Start, Priority: gap.Start})
+		}
+	}
 
-		if fileId == -1 {
-			// skip over free space
-			i -= 1
+	for id := len(d.files) - 1; id >= 0; id-- {
+		file := d.files[id][0]
+		if file.Length == 0 {
 			continue
 		}
 
-		// get number of blocks
-		var blocks = 1
-		for i-blocks >= 0 {
-			if disk[i-blocks] == fileId {
-				blocks += 1
-			} else {
-				break
+		best := -1
+		for length := file.Length; length < len(free); length++ {
+			if free[length].Len() == 0 || free[length][0].Value > file.Start {
+				continue
+			}
+			if best == -1 || free[length][0].Value < free[best][0].Value {
+				best = length
 			}
 		}
 
-		if fileId > minFileId {
-			// we've already moved this file - can be skipped
-			i -= blocks
+		if best == -1 {
 			continue
 		}
 
-		// need to make space for this many blocks
-		var space = 0
-		var partialSpace = false
-		var j int
-		for j = min; j < i-blocks+1; j++ {
-			if disk[j] != -1 {
-				if space > 0 {
-					// space has now ended, but is too small
-					// reset the counter and keep looking
-					space = 0
-					// flag that we've skipped over some space that's too small
-					// as subsequent files might fit here
-					partialSpace = true
-				} else if !partialSpace {
-					// occupied space - don't bother checking this in future files
-					min += 1
-				}
-			} else {
-				space += 1
-				if space == blocks {
-					break
-				}
-			}
+		start := heap.Pop(&free[best]).(*lib.PriorityQueueItem[int]).Value
+		d.files[id] = []Extent{{Start: start, Length: file.Length}}
+		trace(Move{ID: id, From: file.Start, To: start, Length: file.Length})
+
+		if rest := best - file.Length; rest > 0 {
+			heap.Push(&free[rest], &lib.PriorityQueueItem[int]{Value: start + file.Length, Priority: start + file.Length})
 		}
+	}
+}
+
+func (d *Disk) Checksum() int {
+	checksum := 0
+	for id, extents := range d.files {
+		for _, extent := range extents {
+			checksum += extent.Checksum(id)
+		}
+	}
+	return checksum
+}
+
+// Layout returns the file ID stored in each block of the disk, with -1 for
+// free space
+func (d *Disk) Layout() []int {
+	size := 0
+	for _, extents := range d.files {
+		for _, extent := range extents {
+			size = lib.Max(size, extent.Start+extent.Length)
+		}
+	}
 
-		if space == blocks {
-			// we've found somewhere to fit the file
-			for b := range blocks {
-				disk[j-b] = fileId
-				disk[i-b] = -1
+	layout := make([]int, size)
+	for i := range layout {
+		layout[i] = -1
+	}
+	for id, extents := range d.files {
+		for _, extent := range extents {
+			for i := range extent.Length {
+				layout[extent.Start+i] = id
 			}
 		}
+	}
+	return layout
+}
+
+func compact(lines []string, strategy Strategy) (int, error) {
+	disk, err := ParseDisk(lines[0])
+	if err != nil {
+		return 0, err
+	}
+
+	disk.Compact(strategy, nil)
+
+	return disk.Checksum(), nil
+}
+
+func Part1(lines []string) (int, error) {
+	return compact(lines, Blocks)
+}
+
+func Part2(lines []string) (int, error) {
+	return compact(lines, Files)
+}
 
-		minFileId = fileId
-		i -= blocks
+// Expand returns the file ID stored in each block of the disk, with -1 for free space
+func Expand(diskMap string) []int {
+	disk := make([]int, 0, len(diskMap)*4)
+	for i := range len(diskMap) {
+		id := -1
+		if i%2 == 0 {
+			id = i / 2
+		}
+		for range ParseLength(diskMap[i]) {
+			disk = append(disk, id)
+		}
 	}
+	return disk
+}
 
+func Checksum(disk []int) int {
 	var checksum int
 	for i, fileId := range disk {
 		if fileId != -1 {
 			checksum += i * fileId
 		}
 	}
+	return checksum
+}
+
+// Part1Reference is a slow but obviously correct version of Part1, which moves
+// one block at a time from the end of the disk into the leftmost free space
+func Part1Reference(lines []string) (int, error) {
+	disk := Expand(lines[0])
+
+	for {
+		free := slices.Index(disk, -1)
+		last := len(disk) - 1
+		for last >= 0 && disk[last] == -1 {
+			last -= 1
+		}
+
+		if free == -1 || free > last {
+			break
+		}
+
+		disk[free], disk[last] = disk[last], -1
+	}
+
+	return Checksum(disk), nil
+}
+
+// Part2Reference is a slow but obviously correct version of Part2, which scans
+// the whole disk for the leftmost gap each file fits in
+func Part2Reference(lines []string) (int, error) {
+	disk := Expand(lines[0])
+
+	for id := len(lines[0]) / 2; id >= 0; id-- {
+		start := slices.Index(disk, id)
+		if start == -1 {
+			continue
+		}
+		length := 1
+		for start+length < len(disk) && disk[start+length] == id {
+			length += 1
+		}
+
+		for gap := 0; gap+length <= start; gap++ {
+			if slices.ContainsFunc(disk[gap:gap+length], func(block int) bool { return block != -1 }) {
+				continue
+			}
+
+			for i := range length {
+				disk[gap+i], disk[start+i] = id, -1
+			}
+			break
+		}
+	}
 
-	return checksum, nil
+	return Checksum(disk), nil
 }
@@ -1,26 +1,110 @@
 package main
 
 import (
-	"strings"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/max-nicholson/advent-of-code-2024/lib/gen"
+	"github.com/max-nicholson/advent-of-code-2024/lib/testutil"
 )
 
-func TestPart1(t *testing.T) {
-	result, err := Part1(strings.Split(`2333133121414131402`, "\n"))
-	if err != nil {
-		t.Fatal(err)
+func TestExamples(t *testing.T) {
+	testutil.Golden(t, map[string]testutil.Part{
+		"part1": testutil.Lines(Part1),
+		"part2": testutil.Lines(Part2),
+	})
+}
+
+func TestPart1MatchesReference(t *testing.T) {
+	for seed := range uint64(200) {
+		input := []string{gen.Day09(gen.New(seed), 1+int(seed%40))}
+
+		want, err := Part1Reference(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := Part1(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != want {
+			t.Fatalf("disk map %s: got %d, want %d", input[0], got, want)
+		}
 	}
-	if result != 1928 {
-		t.Fatalf("expected 1928, got %d", result)
+}
+
+func TestPart2MatchesReference(t *testing.T) {
+	for seed := range uint64(200) {
+		input := []string{gen.Day09(gen.New(seed), 1+int(seed%40))}
+
+		want, err := Part2Reference(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := Part2(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != want {
+			t.Fatalf("disk map %s: got %d, want %d", input[0], got, want)
+		}
 	}
 }
 
-func TestPart2(t *testing.T) {
-	result, err := Part2(strings.Split(`2333133121414131402`, "\n"))
-	if err != nil {
-		t.Fatal(err)
+func TestTraceReplaysCompaction(t *testing.T) {
+	diskMap := "2333133121414131402"
+
+	for _, strategy := range []Strategy{Blocks, Files} {
+		disk, err := ParseDisk(diskMap)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var moves []Move
+		disk.Compact(strategy, func(m Move) { moves = append(moves, m) })
+
+		replayed := Expand(diskMap)
+		for _, m := range moves {
+			for i := range m.Length {
+				if replayed[m.From+i] != m.ID || replayed[m.To+i] != -1 {
+					t.Fatalf("strategy %d: %v moves blocks that aren't there", strategy, m)
+				}
+				replayed[m.To+i], replayed[m.From+i] = m.ID, -1
+			}
+		}
+
+		layout := disk.Layout()
+		if diff := cmp.Diff(layout, replayed[:len(layout)]); diff != "" {
+			t.Errorf("strategy %d: replayed trace mismatch (-layout +replayed):\n%s", strategy, diff)
+		}
+		if Checksum(replayed) != disk.Checksum() {
+			t.Errorf("strategy %d: got checksum %d, want %d", strategy, disk.Checksum(), Checksum(replayed))
+		}
+	}
+}
+
+func TestParseDiskInvalid(t *testing.T) {
+	if _, err := ParseDisk("12a4"); err == nil {
+		t.Error("want an error for a non-digit length")
 	}
-	if result != 2858 {
-		t.Fatalf("expected 2858, got %d", result)
+}
+
+func BenchmarkCompact(b *testing.B) {
+	diskMap := gen.Day09(gen.New(1), 1_000_000)
+
+	for name, strategy := range map[string]Strategy{"blocks": Blocks, "files": Files} {
+		b.Run(name, func(b *testing.B) {
+			for range b.N {
+				disk, err := ParseDisk(diskMap)
+				if err != nil {
+					b.Fatal(err)
+				}
+				disk.Compact(strategy, nil)
+			}
+		})
 	}
 }
@@ -2,7 +2,10 @@ package main
 
 import (
 	"fmt"
+	"iter"
 	"log"
+	"math/bits"
+	"slices"
 
 	"github.com/max-nicholson/advent-of-code-2024/lib"
 )
@@ -26,7 +29,10 @@ func main() {
 	fmt.Printf("part2: %d\n", part2)
 }
 
-func ParseGrid(lines []string) [][]int {
+// Impassable marks a cell ('.' on the map) that no trail can cross
+const Impassable = -1
+
+func ParseGrid(lines []string) ([][]int, error) {
 	rows := len(lines)
 	columns := len(lines[0])
 	grid := make([][]int, rows)
@@ -34,11 +40,17 @@ func ParseGrid(lines []string) [][]int {
 	for i, line := range lines {
 		grid[i] = make([]int, columns)
 		for j, c := range line {
-			height := int(c - '0')
-			grid[i][j] = height
+			switch {
+			case c == '.':
+				grid[i][j] = Impassable
+			case '0' <= c && c <= '9':
+				grid[i][j] = int(c - '0')
+			default:
+				return nil, fmt.Errorf("invalid height %q at row %d column %d", c, i, j)
+			}
 		}
 	}
-	return grid
+	return grid, nil
 }
 
 type Point struct {
@@ -46,106 +58,232 @@ type Point struct {
 	column int
 }
 
-type Path struct {
-	Point
-	height int
+// Rule says where trails start and end, and how much the height can change
+// with each step. Steps must all climb or all descend, so trails can't loop
+type Rule struct {
+	Start   int
+	End     int
+	MinStep int
+	MaxStep int
 }
 
-func Part1(lines []string) (int, error) {
-	total := 0
+var DefaultRule = Rule{Start: 0, End: 9, MinStep: 1, MaxStep: 1}
 
-	grid := ParseGrid(lines)
-	rows := len(grid)
-	columns := len(grid[0])
+func (r Rule) Allows(from, to int) bool {
+	step := to - from
+	return r.MinStep <= step && step <= r.MaxStep
+}
 
-	for r, row := range grid {
-		for c, node := range row {
-			if node != 0 {
-				continue
-			}
+func (r Rule) validate() error {
+	if r.MinStep <= 0 && r.MaxStep >= 0 {
+		return fmt.Errorf("steps from %d to %d allow trails to loop", r.MinStep, r.MaxStep)
+	}
+	if r.MinStep > r.MaxStep {
+		return fmt.Errorf("minimum step %d is above maximum step %d", r.MinStep, r.MaxStep)
+	}
+	return nil
+}
 
-			seen := make(map[Point]struct{})
-			stack := []Path{{Point: Point{row: r, column: c}, height: 0}}
+// climbs is whether trails go up, rather than down
+func (r Rule) climbs() bool {
+	return r.MinStep > 0
+}
 
-			for len(stack) > 0 {
-				path := stack[len(stack)-1]
-				stack = stack[:len(stack)-1]
+type Map struct {
+	grid          [][]int
+	rows, columns int
+	rule          Rule
+}
 
-				for _, delta := range []Point{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
-					row := delta.row + path.Point.row
-					column := delta.column + path.column
+func NewMap(lines []string, rule Rule) (*Map, error) {
+	if err := rule.validate(); err != nil {
+		return nil, err
+	}
 
-					if row < 0 || row >= rows || column < 0 || column >= columns {
-						continue
-					}
+	grid, err := ParseGrid(lines)
+	if err != nil {
+		return nil, err
+	}
 
-					next := Point{row, column}
+	return &Map{grid: grid, rows: len(grid), columns: len(grid[0]), rule: rule}, nil
+}
 
-					if grid[row][column] != path.height+1 {
-						continue
-					}
+func (m *Map) height(p Point) int {
+	return m.grid[p.row][p.column]
+}
 
-					if _, ok := seen[next]; ok {
-						continue
-					}
+// next yields the neighbours a trail at p can step to
+func (m *Map) next(p Point) iter.Seq[Point] {
+	return func(yield func(Point) bool) {
+		for _, delta := range []Point{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+			n := Point{p.row + delta.row, p.column + delta.column}
+			if n.row < 0 || n.row >= m.rows || n.column < 0 || n.column >= m.columns {
+				continue
+			}
 
-					seen[next] = struct{}{}
+			if m.height(n) == Impassable || !m.rule.Allows(m.height(p), m.height(n)) {
+				continue
+			}
 
-					if grid[row][column] == 9 {
-						total += 1
-					} else {
-						stack = append(stack, Path{Point: next, height: grid[row][column]})
-					}
-				}
+			if !yield(n) {
+				return
 			}
 		}
 	}
+}
 
-	return total, nil
+// Trailhead is a trail start with the number of trail ends it reaches (its
+// score) and the number of distinct trails from it (its rating)
+type Trailhead struct {
+	At     Point
+	Score  int
+	Rating int
 }
 
-func Part2(lines []string) (int, error) {
-	total := 0
+// Trailheads finds every trailhead, top to bottom and left to right.
+//
+// Each step moves the height the same way, so working back from the trail
+// ends in order of height, every cell's neighbours along a trail are finished
+// before it. Each cell counts the trails through it to an end, and keeps a
+// bitset of the ends it can reach, giving ratings and scores in one pass
+func (m *Map) Trailheads() []Trailhead {
+	var ends []Point
+	var cells []Point
+	for r, row := range m.grid {
+		for c, height := range row {
+			if height == m.rule.End {
+				ends = append(ends, Point{r, c})
+			}
+			if height != Impassable {
+				cells = append(cells, Point{r, c})
+			}
+		}
+	}
+
+	// furthest along a trail first
+	slices.SortStableFunc(cells, func(a, b Point) int {
+		if m.rule.climbs() {
+			return m.height(b) - m.height(a)
+		}
+		return m.height(a) - m.height(b)
+	})
+
+	words := (len(ends) + 63) / 64
+	reach := make(map[Point][]uint64, len(cells))
+	ratings := make(map[Point]int, len(cells))
 
-	grid := ParseGrid(lines)
-	rows := len(grid)
-	columns := len(grid[0])
+	for i, end := range ends {
+		reach[end] = make([]uint64, words)
+		reach[end][i/64] |= 1 << (i % 64)
+		ratings[end] = 1
+	}
 
-	for r, row := range grid {
-		for c, node := range row {
-			if node != 0 {
+	for _, cell := range cells {
+		if m.height(cell) == m.rule.End {
+			continue
+		}
+
+		reachable := make([]uint64, words)
+		rating := 0
+		for n := range m.next(cell) {
+			for w, word := range reach[n] {
+				reachable[w] |= word
+			}
+			rating += ratings[n]
+		}
+
+		reach[cell] = reachable
+		ratings[cell] = rating
+	}
+
+	var trailheads []Trailhead
+	for r, row := range m.grid {
+		for c, height := range row {
+			if height != m.rule.Start {
 				continue
 			}
 
-			stack := []Path{{Point: Point{row: r, column: c}, height: 0}}
+			at := Point{r, c}
+			score := 0
+			for _, word := range reach[at] {
+				score += bits.OnesCount64(word)
+			}
+			trailheads = append(trailheads, Trailhead{At: at, Score: score, Rating: ratings[at]})
+		}
+	}
 
-			for len(stack) > 0 {
-				path := stack[len(stack)-1]
-				stack = stack[:len(stack)-1]
+	return trailheads
+}
+
+// Best is the trailhead with the highest score, then the highest rating
+func (m *Map) Best() (Trailhead, bool) {
+	trailheads := m.Trailheads()
+	if len(trailheads) == 0 {
+		return Trailhead{}, false
+	}
+
+	return slices.MaxFunc(trailheads, func(a, b Trailhead) int {
+		if a.Score != b.Score {
+			return a.Score - b.Score
+		}
+		return a.Rating - b.Rating
+	}), true
+}
 
-				for _, delta := range []Point{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
-					row := delta.row + path.Point.row
-					column := delta.column + path.column
+// Trails yields every distinct trail from a trailhead, as the points along it
+func (m *Map) Trails(from Point) iter.Seq[[]Point] {
+	return func(yield func([]Point) bool) {
+		if m.height(from) != m.rule.Start {
+			return
+		}
 
-					if row < 0 || row >= rows || column < 0 || column >= columns {
-						continue
-					}
+		trail := []Point{from}
 
-					next := Point{row, column}
+		var walk func() bool
+		walk = func() bool {
+			current := trail[len(trail)-1]
+			if m.height(current) == m.rule.End {
+				return yield(slices.Clone(trail))
+			}
 
-					if grid[row][column] != path.height+1 {
-						continue
-					}
+			for n := range m.next(current) {
+				trail = append(trail, n)
+				more := walk()
+				trail = trail[:len(trail)-1]
 
-					if grid[row][column] == 9 {
-						total += 1
-					} else {
-						stack = append(stack, Path{Point: next, height: grid[row][column]})
-					}
+				if !more {
+					return false
 				}
 			}
+			return true
 		}
+
+		walk()
 	}
+}
 
+func Part1(lines []string) (int, error) {
+	m, err := NewMap(lines, DefaultRule)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, trailhead := range m.Trailheads() {
+		total += trailhead.Score
+	}
+	return total, nil
+}
+
+func Part2(lines []string) (int, error) {
+	m, err := NewMap(lines, DefaultRule)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, trailhead := range m.Trailheads() {
+		total += trailhead.Rating
+	}
 	return total, nil
 }
@@ -1,40 +1,108 @@
 package main
 
 import (
+	"fmt"
 	"strings"
 	"testing"
+
+	"github.com/max-nicholson/advent-of-code-2024/lib/gen"
+	"github.com/max-nicholson/advent-of-code-2024/lib/testutil"
 )
 
-func TestPart1(t *testing.T) {
-	result, err := Part1(strings.Split(`89010123
-78121874
-87430965
-96549874
-45678903
-32019012
-01329801
-10456732`, "\n"))
+func TestExamples(t *testing.T) {
+	testutil.Golden(t, map[string]testutil.Part{
+		"part1": testutil.Lines(Part1),
+		"part2": testutil.Lines(Part2),
+	})
+}
+
+func TestTrails(t *testing.T) {
+	m, err := NewMap([]string{
+		"..90..9",
+		"...1.98",
+		"...2..7",
+		"6543456",
+		"765.987",
+		"876....",
+		"987....",
+	}, DefaultRule)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if result != 36 {
-		t.Fatalf("expected 36, got %d", result)
+
+	best, ok := m.Best()
+	if !ok {
+		t.Fatal("Best() found no trailheads")
+	}
+
+	want := Trailhead{At: Point{0, 3}, Score: 4, Rating: 13}
+	if best != want {
+		t.Errorf("Best() got %+v, want %+v", best, want)
+	}
+
+	trails := 0
+	seen := make(map[string]struct{})
+	for trail := range m.Trails(best.At) {
+		trails++
+		seen[fmt.Sprint(trail)] = struct{}{}
+
+		for i, p := range trail {
+			if m.height(p) != i {
+				t.Fatalf("trail %v climbs to height %d at step %d", trail, m.height(p), i)
+			}
+		}
+	}
+
+	if trails != best.Rating || len(seen) != trails {
+		t.Errorf("Trails() got %d trails (%d distinct), want %d", trails, len(seen), best.Rating)
 	}
 }
 
-func TestPart2(t *testing.T) {
-	result, err := Part2(strings.Split(`89010123
-78121874
-87430965
-96549874
-45678903
-32019012
-01329801
-10456732`, "\n"))
+func TestRules(t *testing.T) {
+	input, err := gen.Generate(10, 30, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(input, "\n")
+
+	climb, err := NewMap(lines, DefaultRule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	descend, err := NewMap(lines, Rule{Start: 9, End: 0, MinStep: -1, MaxStep: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the same trails, walked the other way
+	total := func(trailheads []Trailhead) (ratings int) {
+		for _, trailhead := range trailheads {
+			ratings += trailhead.Rating
+		}
+		return ratings
+	}
+	if a, b := total(climb.Trailheads()), total(descend.Trailheads()); a != b {
+		t.Errorf("climbing trails got %d ratings, descending got %d", a, b)
+	}
+
+	steep, err := NewMap(lines, Rule{Start: 0, End: 9, MinStep: 1, MaxStep: 2})
 	if err != nil {
 		t.Fatal(err)
 	}
-	if result != 81 {
-		t.Fatalf("expected 81, got %d", result)
+	for _, trailhead := range steep.Trailheads() {
+		ends := make(map[Point]struct{})
+		trails := 0
+		for trail := range steep.Trails(trailhead.At) {
+			ends[trail[len(trail)-1]] = struct{}{}
+			trails++
+		}
+
+		if trailhead.Score != len(ends) || trailhead.Rating != trails {
+			t.Errorf("trailhead %v: got score %d rating %d, walking trails found %d and %d", trailhead.At, trailhead.Score, trailhead.Rating, len(ends), trails)
+		}
+	}
+
+	if _, err := NewMap(lines, Rule{Start: 0, End: 9, MinStep: -1, MaxStep: 1}); err == nil {
+		t.Error("want an error for steps that can loop")
 	}
 }
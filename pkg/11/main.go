@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"log"
+	"math/big"
 	"strconv"
 	"strings"
 
@@ -53,9 +54,12 @@ func Next(stone int) []int {
 }
 
 func Part1(lines []string) (int, error) {
-	stones := ParseStones(lines[0])
+	return count(lines[0], 25)
+}
 
-	for range 25 {
+// BlinkReference counts stones after blinking times by simulating every stone
+func BlinkReference(stones []int, times int) int {
+	for range times {
 		next := make([]int, 0, len(stones))
 
 		for _, stone := range stones {
@@ -65,48 +69,101 @@ func Part1(lines []string) (int, error) {
 		stones = next
 	}
 
-	return len(stones), nil
+	return len(stones)
 }
 
-type CacheKey struct {
-	stone int
-	times int
+// Rules decide what a stone becomes each time you blink. Stones are written
+// as decimal numbers without leading zeros, so they can grow past an int
+type Rules interface {
+	Blink(stone string) []string
 }
 
-func Blink(cache map[CacheKey]int, stone int, times int) int {
-	key := CacheKey{stone, times}
-	v, ok := cache[key]
-	if ok {
-		return v
+// Standard are the rules engraved on the stones
+type Standard struct{}
+
+var multiplier = big.NewInt(2024)
+
+func (Standard) Blink(stone string) []string {
+	switch {
+	case stone == "0":
+		return []string{"1"}
+	case len(stone)%2 == 0:
+		right := strings.TrimLeft(stone[len(stone)/2:], "0")
+		if right == "" {
+			right = "0"
+		}
+		return []string{stone[:len(stone)/2], right}
+	case len(stone) <= 15:
+		// small enough to multiply without overflowing
+		n, _ := strconv.Atoi(stone)
+		return []string{strconv.Itoa(n * 2024)}
 	}
 
-	if times == 1 {
-		l := len(Next(stone))
-		cache[key] = l
-		return l
+	n, _ := new(big.Int).SetString(stone, 10)
+	return []string{n.Mul(n, multiplier).String()}
+}
+
+// Distribution counts how many stones have each value. Stones never affect
+// each other, so stepping the counts is the same as stepping every stone
+type Distribution map[string]*big.Int
+
+func NewDistribution(stones []string) Distribution {
+	d := make(Distribution, len(stones))
+	for _, stone := range stones {
+		d.add(stone, big.NewInt(1))
 	}
+	return d
+}
 
-	next := Next(stone)
+func (d Distribution) add(stone string, n *big.Int) {
+	if count, ok := d[stone]; ok {
+		count.Add(count, n)
+	} else {
+		d[stone] = new(big.Int).Set(n)
+	}
+}
 
-	var sum int
-	for _, s := range next {
-		t := Blink(cache, s, times-1)
-		cache[CacheKey{s, times - 1}] = t
-		sum += t
+// Blink returns the distribution after blinking once. Each distinct value is
+// only passed through the rules once, however many stones have it
+func (d Distribution) Blink(rules Rules) Distribution {
+	next := make(Distribution, len(d))
+	for stone, count := range d {
+		for _, s := range rules.Blink(stone) {
+			next.add(s, count)
+		}
 	}
-	cache[key] = sum
-	return sum
+	return next
 }
 
-func Part2(lines []string) (int, error) {
-	stones := ParseStones(lines[0])
+func (d Distribution) BlinkTimes(rules Rules, times int) Distribution {
+	for range times {
+		d = d.Blink(rules)
+	}
+	return d
+}
 
-	var total int
-	cache := map[CacheKey]int{}
+// Total is the number of stones
+func (d Distribution) Total() *big.Int {
+	total := new(big.Int)
+	for _, count := range d {
+		total.Add(total, count)
+	}
+	return total
+}
 
-	for _, stone := range stones {
-		total += Blink(cache, stone, 75)
+// Distinct is the number of different values engraved on the stones
+func (d Distribution) Distinct() int {
+	return len(d)
+}
+
+func count(line string, times int) (int, error) {
+	total := NewDistribution(strings.Fields(line)).BlinkTimes(Standard{}, times).Total()
+	if !total.IsInt64() {
+		return 0, fmt.Errorf("%s stones is too many to count in an int", total)
 	}
+	return int(total.Int64()), nil
+}
 
-	return total, nil
+func Part2(lines []string) (int, error) {
+	return count(lines[0], 75)
 }
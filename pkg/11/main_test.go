@@ -1,16 +1,92 @@
 package main
 
 import (
+	"math/big"
 	"strings"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/max-nicholson/advent-of-code-2024/lib/gen"
+	"github.com/max-nicholson/advent-of-code-2024/lib/testutil"
 )
 
-func TestPart1(t *testing.T) {
-	result, err := Part1(strings.Split(`125 17`, "\n"))
-	if err != nil {
-		t.Fatal(err)
+func TestExamples(t *testing.T) {
+	testutil.Golden(t, map[string]testutil.Part{
+		"part1": testutil.Lines(Part1),
+		"part2": testutil.Lines(Part2),
+	})
+}
+
+func TestBlinkMatchesReference(t *testing.T) {
+	for seed := range uint64(50) {
+		line := gen.Day11(gen.New(seed), 1+int(seed%5))
+		times := 1 + int(seed%15)
+
+		want := BlinkReference(ParseStones(line), times)
+
+		got := NewDistribution(strings.Fields(line)).BlinkTimes(Standard{}, times).Total()
+		if !got.IsInt64() || int(got.Int64()) != want {
+			t.Fatalf("stones %s after %d blinks: got %s, want %d", line, times, got, want)
+		}
+	}
+}
+
+func TestDistribution(t *testing.T) {
+	d := NewDistribution([]string{"125", "17"}).BlinkTimes(Standard{}, 6)
+
+	// 2097446912 14168 4048 2 0 2 4 40 48 2024 40 48 80 96 2 8 6 7 6 0 3 2
+	want := map[string]int64{
+		"2097446912": 1, "14168": 1, "4048": 1, "2": 4, "0": 2, "4": 1,
+		"40": 2, "48": 2, "2024": 1, "80": 1, "96": 1, "8": 1, "6": 2, "7": 1, "3": 1,
+	}
+
+	got := make(map[string]int64, len(d))
+	for stone, count := range d {
+		got[stone] = count.Int64()
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("BlinkTimes() mismatch (-want +got):\n%s", diff)
+	}
+
+	if d.Distinct() != 15 {
+		t.Errorf("Distinct() got %d, want 15", d.Distinct())
+	}
+}
+
+func TestBigStones(t *testing.T) {
+	d := NewDistribution([]string{"1234567890123456789", "100000000000000000000000"})
+
+	d = d.Blink(Standard{})
+	want := []string{"2498765409609876540936", "100000000000", "0"}
+	for _, stone := range want {
+		if _, ok := d[stone]; !ok {
+			t.Errorf("want %s after one blink, got %v", stone, d)
+		}
+	}
+
+	// far more stones than fit in an int
+	total := NewDistribution([]string{"125", "17"}).BlinkTimes(Standard{}, 1000).Total()
+	if total.IsInt64() {
+		t.Errorf("want more than an int of stones after 1000 blinks, got %s", total)
+	}
+}
+
+// doubling splits every stone into two copies of itself
+type doubling struct{}
+
+func (doubling) Blink(stone string) []string {
+	return []string{stone, stone}
+}
+
+func TestCustomRules(t *testing.T) {
+	d := NewDistribution([]string{"1", "2", "2"}).BlinkTimes(doubling{}, 100)
+
+	want := new(big.Int).Lsh(big.NewInt(3), 100)
+	if d.Total().Cmp(want) != 0 {
+		t.Errorf("Total() got %s, want %s", d.Total(), want)
 	}
-	if result != 55312 {
-		t.Fatalf("expected 55312, got %d", result)
+	if d.Distinct() != 2 {
+		t.Errorf("Distinct() got %d, want 2", d.Distinct())
 	}
 }
@@ -3,50 +3,11 @@ package main
 import (
 	"fmt"
 	"log"
+	"slices"
 
 	"github.com/max-nicholson/advent-of-code-2024/lib"
 )
 
-type Plane int
-
-const (
-	Horizontal Plane = iota + 1
-	Vertical
-)
-
-type Edge int
-
-const (
-	Top Edge = iota + 1
-	Bottom
-	Left
-	Right
-)
-
-func (e Edge) Plane() Plane {
-	switch e {
-	case Top:
-		return Horizontal
-	case Bottom:
-		return Horizontal
-	case Left:
-		return Vertical
-	case Right:
-		return Vertical
-	}
-	panic(fmt.Sprintf("invalid edge %v", e))
-}
-
-func (e Edge) Deltas() []Point {
-	switch e.Plane() {
-	case Horizontal:
-		return []Point{{0, 1}, {0, -1}}
-	case Vertical:
-		return []Point{{1, 0}, {-1, 0}}
-	}
-	panic("invalid plane")
-}
-
 func main() {
 	lines, err := lib.ReadLines("pkg/12/input.txt")
 	if err != nil {
@@ -71,199 +32,285 @@ type Point struct {
 	column int
 }
 
-func (p Point) Adjacent(edge Edge) Point {
-	switch edge {
-	case Top:
-		return Point{p.row - 1, p.column}
-	case Bottom:
-		return Point{p.row + 1, p.column}
-	case Left:
-		return Point{p.row, p.column - 1}
-	case Right:
-		return Point{p.row, p.column + 1}
-	}
-	panic("invalid edge")
-}
-
 var CARDINAL_DIRECTIONS = []Point{{-1, 0}, {0, -1}, {1, 0}, {0, 1}}
 
-type Region struct {
-	plots map[Point]struct{}
-	plant byte
-}
+var ALL_DIRECTIONS = []Point{{-1, -1}, {-1, 0}, {-1, 1}, {0, -1}, {0, 1}, {1, -1}, {1, 0}, {1, 1}}
 
-func (r Region) Add(point Point) {
-	r.plots[point] = struct{}{}
+// Rect is an inclusive box of plots
+type Rect struct {
+	Top, Left, Bottom, Right int
 }
 
-func (r Region) Area() int {
-	return len(r.plots)
+func (r Rect) Contains(p Point) bool {
+	return r.Top <= p.row && p.row <= r.Bottom && r.Left <= p.column && p.column <= r.Right
 }
 
-type Fence struct {
-	start Point
-	end   Point
+type Region struct {
+	ID        int
+	Plant     byte
+	Area      int
+	Perimeter int
+	// Sides is the number of straight fence sections, which is the same as
+	// the number of corners
+	Sides  int
+	Bounds Rect
+	// Holes are the pockets of other plots the region surrounds
+	Holes int
+	plots []int
 }
 
-func (f Fence) Plane() Plane {
-	if f.start.row == f.end.row {
-		return Horizontal
-	} else {
-		return Vertical
-	}
+// Garden labels each plot with the region it belongs to. Plots are stored
+// in a flat array, indexed row*columns + column
+type Garden struct {
+	rows, columns int
+	plants        []byte
+	labels        []int
+	regions       []Region
+	// parents[id] is the region immediately surrounding region id, or -1
+	parents []int
 }
 
-func (r Region) Fences() map[Fence]struct{} {
-	perimeter := map[Fence]struct{}{}
+func NewGarden(grid []string) *Garden {
+	g := &Garden{rows: len(grid), columns: len(grid[0])}
+	g.plants = make([]byte, 0, g.rows*g.columns)
+	for _, line := range grid {
+		g.plants = append(g.plants, line...)
+	}
 
-	for p := range r.plots {
-		for _, delta := range CARDINAL_DIRECTIONS {
-			neighbour := Point{row: delta.row + p.row, column: delta.column + p.column}
-
-			if _, ok := r.plots[neighbour]; !ok {
-				var fence Fence
-				if delta.row == -1 {
-					// Horizontal above
-					fence.start = p
-					fence.end = Point{p.row, p.column + 1}
-				} else if delta.row == 1 {
-					// Horizontal below
-					fence.start = neighbour
-					fence.end = Point{neighbour.row, neighbour.column + 1}
-				} else if delta.column == -1 {
-					// Vertical left
-					fence.start = p
-					fence.end = Point{p.row + 1, p.column}
-				} else {
-					// Vertical right
-					fence.start = neighbour
-					fence.end = Point{neighbour.row + 1, neighbour.column}
-				}
+	// join each plot to the plots right and below with the same plant
+	set := lib.NewDisjointSet(len(g.plants))
+	for i, plant := range g.plants {
+		if (i+1)%g.columns != 0 && g.plants[i+1] == plant {
+			set.Union(i, i+1)
+		}
+		if i+g.columns < len(g.plants) && g.plants[i+g.columns] == plant {
+			set.Union(i, i+g.columns)
+		}
+	}
 
-				perimeter[fence] = struct{}{}
-				continue
-			}
+	// number regions in reading order of their first plot
+	g.labels = make([]int, len(g.plants))
+	ids := make(map[int]int)
+	for i := range g.plants {
+		root := set.Find(i)
+		id, ok := ids[root]
+		if !ok {
+			id = len(g.regions)
+			ids[root] = id
+			p := g.point(i)
+			g.regions = append(g.regions, Region{
+				ID:     id,
+				Plant:  g.plants[i],
+				Bounds: Rect{Top: p.row, Left: p.column, Bottom: p.row, Right: p.column},
+			})
 		}
+		g.labels[i] = id
+		g.regions[id].plots = append(g.regions[id].plots, i)
 	}
 
-	return perimeter
+	for id := range g.regions {
+		g.measure(&g.regions[id])
+	}
+	g.nest()
+
+	return g
+}
+
+func (g *Garden) point(i int) Point {
+	return Point{i / g.columns, i % g.columns}
 }
 
-func (r Region) Perimeter() int {
-	return len(r.Fences())
+func (g *Garden) InBounds(p Point) bool {
+	return 0 <= p.row && p.row < g.rows && 0 <= p.column && p.column < g.columns
 }
 
-func (r Region) Sides() int {
-	var sides int
-	type visit struct {
-		Point
-		edge Edge
+// label is the region at p, or -1 outside the garden
+func (g *Garden) label(p Point) int {
+	if !g.InBounds(p) {
+		return -1
 	}
-	visited := map[visit]struct{}{}
+	return g.labels[p.row*g.columns+p.column]
+}
 
-	for p := range r.plots {
-		var current Point
-		for _, edge := range []Edge{Top, Bottom, Left, Right} {
-			if _, ok := visited[visit{Point: p, edge: edge}]; ok {
-				continue
-			}
+// measure finds the area, perimeter, sides and bounds of a region.
+//
+// Every side ends in two corners and every corner joins two sides, so sides
+// are counted by looking at the corners of each plot. A corner is outside if
+// neither neighbour next to it is in the region, and inside if both are but
+// the diagonal between them isn't
+func (g *Garden) measure(region *Region) {
+	region.Area = len(region.plots)
+
+	for _, i := range region.plots {
+		p := g.point(i)
+		in := func(dr, dc int) bool {
+			return g.label(Point{p.row + dr, p.column + dc}) == region.ID
+		}
 
-			if _, ok := r.plots[p.Adjacent(edge)]; ok {
-				continue
+		for _, delta := range CARDINAL_DIRECTIONS {
+			if !in(delta.row, delta.column) {
+				region.Perimeter += 1
 			}
+		}
 
-			visited[visit{Point: p, edge: edge}] = struct{}{}
-			for _, delta := range edge.Deltas() {
-				current = p
-				for {
-					next := Point{current.row + delta.row, current.column + delta.column}
-					if _, ok := r.plots[next]; !ok {
-						break
-					}
-
-					if _, ok := r.plots[next.Adjacent(edge)]; ok {
-						break
-					}
-
-					visited[visit{Point: next, edge: edge}] = struct{}{}
-					current = next
+		for _, dr := range []int{-1, 1} {
+			for _, dc := range []int{-1, 1} {
+				vertical, horizontal, diagonal := in(dr, 0), in(0, dc), in(dr, dc)
+				if (!vertical && !horizontal) || (vertical && horizontal && !diagonal) {
+					region.Sides += 1
 				}
 			}
-			sides += 1
 		}
-	}
 
-	return sides
+		region.Bounds.Top = min(region.Bounds.Top, p.row)
+		region.Bounds.Bottom = max(region.Bounds.Bottom, p.row)
+		region.Bounds.Left = min(region.Bounds.Left, p.column)
+		region.Bounds.Right = max(region.Bounds.Right, p.column)
+	}
 }
 
-func NewRegion(plant byte) Region {
-	return Region{plant: plant, plots: map[Point]struct{}{}}
-}
+// nest finds the holes in each region, and which regions sit in them.
+//
+// A region is joined up through its sides, so plots outside it can slip
+// between two region plots that only touch at a corner. Flooding out from
+// beyond the region's bounds across all 8 directions reaches everything that
+// isn't in a hole
+func (g *Garden) nest() {
+	g.parents = make([]int, len(g.regions))
+	// the size of the hole each region's parent surrounds it with
+	pockets := make([]int, len(g.regions))
+	for id := range g.parents {
+		g.parents[id] = -1
+	}
 
-type Garden struct {
-	regions []Region
-}
+	for id := range g.regions {
+		region := &g.regions[id]
+		bounds := Rect{
+			Top:    region.Bounds.Top - 1,
+			Left:   region.Bounds.Left - 1,
+			Bottom: region.Bounds.Bottom + 1,
+			Right:  region.Bounds.Right + 1,
+		}
+
+		outside := map[Point]struct{}{{bounds.Top, bounds.Left}: {}}
+		g.flood(id, bounds, Point{bounds.Top, bounds.Left}, outside)
+
+		seen := make(map[Point]struct{})
+		for row := region.Bounds.Top; row <= region.Bounds.Bottom; row++ {
+			for column := region.Bounds.Left; column <= region.Bounds.Right; column++ {
+				p := Point{row, column}
+				if g.label(p) == id {
+					continue
+				}
+				if _, ok := outside[p]; ok {
+					continue
+				}
+				if _, ok := seen[p]; ok {
+					continue
+				}
 
-func NewGarden(grid []string) Garden {
-	garden := Garden{regions: []Region{}}
-	rows := len(grid)
-	columns := len(grid[0])
+				hole := map[Point]struct{}{p: {}}
+				g.flood(id, bounds, p, hole)
+				region.Holes += 1
 
-	visited := map[Point]struct{}{}
+				for q := range hole {
+					seen[q] = struct{}{}
 
-	for r := range rows {
-		for c := range columns {
-			plant := grid[r][c]
-			point := Point{row: r, column: c}
-			if _, ok := visited[point]; ok {
+					// the smallest hole around a region is its parent's
+					inner := g.label(q)
+					if g.parents[inner] == -1 || len(hole) < pockets[inner] {
+						g.parents[inner] = id
+						pockets[inner] = len(hole)
+					}
+				}
+			}
+		}
+	}
+}
+
+// flood fills visited with every plot in bounds reachable from start in any
+// of the 8 directions without crossing region id
+func (g *Garden) flood(id int, bounds Rect, start Point, visited map[Point]struct{}) {
+	stack := []Point{start}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		for _, delta := range ALL_DIRECTIONS {
+			next := Point{p.row + delta.row, p.column + delta.column}
+			if !bounds.Contains(next) || g.label(next) == id {
+				continue
+			}
+			if _, ok := visited[next]; ok {
 				continue
 			}
 
-			region := NewRegion(plant)
+			visited[next] = struct{}{}
+			stack = append(stack, next)
+		}
+	}
+}
 
-			stack := []Point{point}
-			for len(stack) > 0 {
-				p := stack[len(stack)-1]
-				stack = stack[:len(stack)-1]
+// Regions are ordered by their first plot, top to bottom and left to right
+func (g *Garden) Regions() []Region {
+	return g.regions
+}
 
-				if grid[p.row][p.column] != plant {
-					continue
-				}
+func (g *Garden) RegionAt(row, column int) (Region, bool) {
+	id := g.label(Point{row, column})
+	if id == -1 {
+		return Region{}, false
+	}
+	return g.regions[id], true
+}
 
-				region.Add(p)
-				visited[p] = struct{}{}
+// Convex is whether every row and column of the region is unbroken
+func (g *Garden) Convex(id int) bool {
+	region := g.regions[id]
 
-				for _, delta := range CARDINAL_DIRECTIONS {
-					next := Point{row: delta.row + p.row, column: delta.column + p.column}
+	rows := make(map[int][]int)
+	columns := make(map[int][]int)
+	for _, i := range region.plots {
+		p := g.point(i)
+		rows[p.row] = append(rows[p.row], p.column)
+		columns[p.column] = append(columns[p.column], p.row)
+	}
 
-					if next.row < 0 || next.row >= rows || next.column < 0 || next.column >= columns {
-						continue
-					}
+	for _, lines := range []map[int][]int{rows, columns} {
+		for _, line := range lines {
+			if slices.Max(line)-slices.Min(line)+1 != len(line) {
+				return false
+			}
+		}
+	}
+	return true
+}
 
-					if _, ok := visited[next]; ok {
-						continue
-					}
+// Enclosing is the region immediately surrounding a region, if any
+func (g *Garden) Enclosing(id int) (int, bool) {
+	return g.parents[id], g.parents[id] != -1
+}
 
-					if grid[next.row][next.column] == plant {
-						stack = append(stack, next)
-					}
-				}
+// Enclosed lists every region inside one of the region's holes, however
+// deeply nested
+func (g *Garden) Enclosed(id int) []int {
+	var enclosed []int
+	for inner := range g.regions {
+		for parent := g.parents[inner]; parent != -1; parent = g.parents[parent] {
+			if parent == id {
+				enclosed = append(enclosed, inner)
+				break
 			}
-
-			garden.regions = append(garden.regions, region)
 		}
 	}
-
-	return garden
+	return enclosed
 }
 
 func Part1(grid []string) (int, error) {
 	totalPrice := 0
 
-	garden := NewGarden(grid)
-
-	for _, region := range garden.regions {
-		totalPrice += region.Area() * region.Perimeter()
+	for _, region := range NewGarden(grid).Regions() {
+		totalPrice += region.Area * region.Perimeter
 	}
 
 	return totalPrice, nil
@@ -272,14 +319,8 @@ func Part1(grid []string) (int, error) {
 func Part2(grid []string) (int, error) {
 	totalPrice := 0
 
-	garden := NewGarden(grid)
-
-	for _, region := range garden.regions {
-		area := region.Area()
-		sides := region.Sides()
-		price := area * sides
-		// fmt.Printf("%s = %d * %d = %d\n", string(region.plant), area, sides, price)
-		totalPrice += price
+	for _, region := range NewGarden(grid).Regions() {
+		totalPrice += region.Area * region.Sides
 	}
 
 	return totalPrice, nil
@@ -1,74 +1,99 @@
 package main
 
 import (
-	"strconv"
-	"strings"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/max-nicholson/advent-of-code-2024/lib/testutil"
 )
 
-func TestPart1(t *testing.T) {
-	result, err := Part1(strings.Split(`RRRRIICCFF
-RRRRIICCCF
-VVRRRCCFFF
-VVRCCCJFFF
-VVVVCJJCFE
-VVIVCCJJEE
-VVIIICJJEE
-MIIIIIJJEE
-MIIISIJEEE
-MMMISSJEEE`, "\n"))
-	if err != nil {
-		t.Fatal(err)
+func TestExamples(t *testing.T) {
+	testutil.Golden(t, map[string]testutil.Part{
+		"part1": testutil.Lines(Part1),
+		"part2": testutil.Lines(Part2),
+	})
+}
+
+func TestGardenNesting(t *testing.T) {
+	garden := NewGarden([]string{
+		"AAAAAAA",
+		"ABBBBBA",
+		"ABCCCBA",
+		"ABCDCBA",
+		"ABCCCBA",
+		"ABBBBBA",
+		"AAAAAAA",
+		"EEEEEEE",
+	})
+
+	ids := make(map[byte]int)
+	for _, region := range garden.Regions() {
+		ids[region.Plant] = region.ID
 	}
-	if result != 1930 {
-		t.Fatalf("expected 1930, got %d", result)
+
+	parents := map[byte]byte{'B': 'A', 'C': 'B', 'D': 'C'}
+	for inner, outer := range parents {
+		parent, ok := garden.Enclosing(ids[inner])
+		if !ok || parent != ids[outer] {
+			t.Errorf("Enclosing(%c) got %d %v, want %c", inner, parent, ok, outer)
+		}
+	}
+
+	for _, plant := range []byte{'A', 'E'} {
+		if parent, ok := garden.Enclosing(ids[plant]); ok {
+			t.Errorf("Enclosing(%c) got %d, want none", plant, parent)
+		}
 	}
-}
 
-func TestPart2(t *testing.T) {
-	type test struct {
-		input string
-		want  int
+	if diff := cmp.Diff([]int{ids['B'], ids['C'], ids['D']}, garden.Enclosed(ids['A'])); diff != "" {
+		t.Errorf("Enclosed(A) mismatch (-want +got):\n%s", diff)
 	}
-	tests := []test{
-		{input: `AAAA
-BBCD
-BBCC
-EEEC`,
-			want: 80},
-		{input: `EEEEE
-EXXXX
-EEEEE
-EXXXX
-EEEEE`,
-			want: 236},
-		{input: `AAAAAA
-AAABBA
-AAABBA
-ABBAAA
-ABBAAA
-AAAAAA`,
-			want: 368},
-		{input: `RRRRIICCFF
-RRRRIICCCF
-VVRRRCCFFF
-VVRCCCJFFF
-VVVVCJJCFE
-VVIVCCJJEE
-VVIIICJJEE
-MIIIIIJJEE
-MIIISIJEEE
-MMMISSJEEE`, want: 1206},
+
+	a, _ := garden.RegionAt(0, 0)
+	if a.Holes != 1 || a.Area != 24 || a.Sides != 8 {
+		t.Errorf("got A with %d holes, area %d, %d sides, want 1, 24, 8", a.Holes, a.Area, a.Sides)
+	}
+	if a.Bounds != (Rect{Top: 0, Left: 0, Bottom: 6, Right: 6}) {
+		t.Errorf("got A bounds %+v", a.Bounds)
+	}
+}
+
+func TestGardenHolesAndConvexity(t *testing.T) {
+	garden := NewGarden([]string{
+		"AAAAAA",
+		"AAABBA",
+		"AAABBA",
+		"ABBAAA",
+		"ABBAAA",
+		"AAAAAA",
+		"CCDCCC",
+		"CCCCDD",
+	})
+
+	cases := []struct {
+		row, column int
+		holes       int
+		convex      bool
+	}{
+		// the two B regions touch at a corner, so they share one hole
+		{row: 0, column: 0, holes: 1, convex: false},
+		{row: 1, column: 3, holes: 0, convex: true},
+		{row: 6, column: 0, holes: 0, convex: false},
+		{row: 6, column: 2, holes: 0, convex: true},
+		{row: 7, column: 4, holes: 0, convex: true},
 	}
-	for i, tc := range tests {
-		t.Run(strconv.Itoa(i), func(t *testing.T) {
-			got, err := Part2(strings.Split(tc.input, "\n"))
-			if err != nil {
-				t.Fatal(err)
-			}
-			if got != tc.want {
-				t.Fatalf("test: %d; expected %d, got %d", i+1, tc.want, got)
-			}
-		})
+
+	for _, c := range cases {
+		region, ok := garden.RegionAt(c.row, c.column)
+		if !ok {
+			t.Fatalf("no region at %d,%d", c.row, c.column)
+		}
+
+		if region.Holes != c.holes {
+			t.Errorf("%c at %d,%d: got %d holes, want %d", region.Plant, c.row, c.column, region.Holes, c.holes)
+		}
+		if garden.Convex(region.ID) != c.convex {
+			t.Errorf("%c at %d,%d: got convex %v, want %v", region.Plant, c.row, c.column, !c.convex, c.convex)
+		}
 	}
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"math"
@@ -31,9 +32,12 @@ func main() {
 }
 
 type Button struct {
+	name string
 	x    int
 	y    int
 	cost int
+	// limit is the most times the button can be pressed, or 0 for no limit
+	limit int
 }
 
 type Prize struct {
@@ -42,56 +46,452 @@ type Prize struct {
 }
 
 type Machine struct {
-	A, B  Button
-	Prize Prize
+	Buttons []Button
+	Prize   Prize
 }
 
-func InvertMatrix(matrix [2][2]int) [2][2]float64 {
-	a := matrix[0][0]
-	b := matrix[0][1]
-	c := matrix[1][0]
-	d := matrix[1][1]
+// Costs are the tokens it takes to press each button, by name
+type Costs map[string]int
 
-	determinant := float64(1) / float64(a*d-b*c)
+// DefaultCosts are the costs of the puzzle's A and B buttons
+var DefaultCosts = Costs{"A": 3, "B": 1}
 
-	return [2][2]float64{
-		{determinant * float64(d), determinant * float64(-b)},
-		{determinant * float64(-c), determinant * float64(a)},
+// PrizeOffset is how far off the prize positions were measured in part 2
+const PrizeOffset = 10000000000000
+
+// Offset moves the prize n further along both axes
+func (m Machine) Offset(n int) Machine {
+	m.Prize.x += n
+	m.Prize.y += n
+	return m
+}
+
+// Limit caps the number of presses of every button, keeping any lower limit a
+// button already has
+func (m Machine) Limit(presses int) Machine {
+	buttons := make([]Button, len(m.Buttons))
+	for i, button := range m.Buttons {
+		if button.limit == 0 || button.limit > presses {
+			button.limit = presses
+		}
+		buttons[i] = button
 	}
+	m.Buttons = buttons
+	return m
 }
 
-const epsilon = 1e-4 // Margin of error
+var ErrNoPrize = errors.New("prize can't be won")
 
-func IsWholeNumber(n float64) bool {
-	if _, frac := math.Modf(math.Abs(n)); frac < epsilon || frac > 1.0-epsilon {
-		return true
-	} else {
+// ErrSearchTooLarge is when there are too many presses of the extra buttons to
+// try them all
+var ErrSearchTooLarge = errors.New("too many presses to search")
+
+// maxSearch is the most combinations of presses of the extra free buttons Solve
+// will try
+const maxSearch = 1 << 24
+
+// Solution is the cheapest way to win the prize
+type Solution struct {
+	Presses []int
+	Tokens  int
+	// Unique is false when other presses win the prize for the same tokens
+	Unique bool
+}
+
+// Solve finds the cheapest presses to win the prize exactly.
+//
+// Two buttons that aren't parallel (or one, if they all are) are pivots, and
+// every other button is free. With the presses of the free buttons chosen,
+// the pivots' presses follow by Cramer's rule. The last free button is
+// solved in closed form rather than tried press by press: each pivot's
+// presses are (A - B*t) / D for t presses of it, so the valid t are an
+// interval (no button goes below 0 or over its limit) intersected with the
+// residues mod D where every pivot divides exactly. Tokens are linear in t,
+// so the cheapest is at one end. Any other free buttons are tried in turn,
+// which keeps the search small for a couple of extra buttons, but would take
+// forever when the prize is far away, so Solve gives up with
+// ErrSearchTooLarge past maxSearch combinations
+func (m Machine) Solve() (Solution, error) {
+	pivots := m.pivots()
+	if len(pivots) == 0 {
+		if m.Prize != (Prize{}) {
+			return Solution{}, ErrNoPrize
+		}
+		return m.stationary()
+	}
+
+	if len(pivots) == 1 && !m.parallel(m.Buttons[pivots[0]], m.Prize.x, m.Prize.y) {
+		return Solution{}, ErrNoPrize
+	}
+
+	var free []int
+	for i := range m.Buttons {
+		if i != pivots[0] && (len(pivots) == 1 || i != pivots[1]) {
+			free = append(free, i)
+		}
+	}
+
+	bounds := make([]int, len(m.Buttons))
+	for i := range m.Buttons {
+		bounds[i] = m.bound(i)
+	}
+
+	// the free button with the most presses to try is solved in closed form
+	last := -1
+	for _, i := range free {
+		if last == -1 || bounds[i] > bounds[last] {
+			last = i
+		}
+	}
+
+	var outer []int
+	combinations := 1
+	for _, i := range free {
+		if i != last {
+			if bounds[i] == math.MaxInt {
+				return Solution{}, fmt.Errorf("button %s can be pressed without limit", m.Buttons[i].name)
+			}
+			if bounds[i] >= maxSearch/combinations {
+				return Solution{}, fmt.Errorf("%w: button %s can be pressed up to %d times", ErrSearchTooLarge, m.Buttons[i].name, bounds[i])
+			}
+			combinations *= bounds[i] + 1
+			outer = append(outer, i)
+		}
+	}
+
+	best := Solution{Tokens: -1}
+	optima := 0
+	presses := make([]int, len(m.Buttons))
+
+	var search func(k int, x, y int) error
+	search = func(k int, x, y int) error {
+		if k < len(outer) {
+			b := m.Buttons[outer[k]]
+			for n := 0; n <= bounds[outer[k]]; n++ {
+				presses[outer[k]] = n
+				if err := search(k+1, x-n*b.x, y-n*b.y); err != nil {
+					return err
+				}
+			}
+			presses[outer[k]] = 0
+			return nil
+		}
+
+		count, err := m.solveLast(pivots, last, x, y, presses)
+		if err != nil || count == 0 {
+			return err
+		}
+
+		tokens := 0
+		for i, n := range presses {
+			tokens += n * m.Buttons[i].cost
+		}
+
+		switch {
+		case best.Tokens == -1 || tokens < best.Tokens:
+			best = Solution{Presses: append([]int{}, presses...), Tokens: tokens}
+			optima = count
+		case tokens == best.Tokens:
+			optima += count
+		}
+		return nil
+	}
+
+	if err := search(0, m.Prize.x, m.Prize.y); err != nil {
+		return Solution{}, err
+	}
+
+	if best.Tokens == -1 {
+		return Solution{}, ErrNoPrize
+	}
+
+	best.Unique = optima == 1
+	return best, nil
+}
+
+// stationary is the cheapest presses when no button moves the claw
+func (m Machine) stationary() (Solution, error) {
+	solution := Solution{Presses: make([]int, len(m.Buttons)), Unique: true}
+	for i, button := range m.Buttons {
+		switch {
+		case button.cost == 0:
+			solution.Unique = false
+		case button.cost < 0 && button.limit == 0:
+			return Solution{}, fmt.Errorf("pressing button %s more always costs less", button.name)
+		case button.cost < 0:
+			solution.Presses[i] = button.limit
+			solution.Tokens += button.limit * button.cost
+		}
+	}
+	return solution, nil
+}
+
+func (m Machine) pivots() []int {
+	for i, a := range m.Buttons {
+		for j := i + 1; j < len(m.Buttons); j++ {
+			b := m.Buttons[j]
+			if a.x*b.y-a.y*b.x != 0 {
+				return []int{i, j}
+			}
+		}
+	}
+
+	for i, a := range m.Buttons {
+		if a.x != 0 || a.y != 0 {
+			return []int{i}
+		}
+	}
+	return nil
+}
+
+// parallel is whether x, y is a whole multiple of the button's smallest step
+func (m Machine) parallel(b Button, x, y int) bool {
+	gx, gy := m.step(b)
+	if x*gy != y*gx {
 		return false
 	}
+	if gx != 0 {
+		return x%gx == 0
+	}
+	return y%gy == 0
 }
 
-func (m Machine) MinTokensForPrize() int {
-	inverse := InvertMatrix([2][2]int{
-		{m.A.x, m.B.x},
-		{m.A.y, m.B.y},
-	})
+// step is the smallest whole vector in the button's direction
+func (m Machine) step(b Button) (int, int) {
+	g := lib.GCD(b.x, b.y)
+	return b.x / g, b.y / g
+}
+
+// scale is how many steps of the pivot's direction make up x, y
+func (m Machine) scale(pivot Button, x, y int) int {
+	gx, gy := m.step(pivot)
+	if gx != 0 {
+		return x / gx
+	}
+	return y / gy
+}
+
+// bound is the most a button can usefully be pressed, from its limit or from
+// how far the prize is when every button only moves the claw forwards
+func (m Machine) bound(i int) int {
+	bound := math.MaxInt
+	if m.Buttons[i].limit > 0 {
+		bound = m.Buttons[i].limit
+	}
+
+	forwards := func(axis func(Button) int, prize int) bool {
+		for _, b := range m.Buttons {
+			if axis(b) < 0 {
+				return false
+			}
+		}
+		return prize >= 0 && axis(m.Buttons[i]) > 0
+	}
+
+	if forwards(func(b Button) int { return b.x }, m.Prize.x) {
+		bound = min(bound, m.Prize.x/m.Buttons[i].x)
+	}
+	if forwards(func(b Button) int { return b.y }, m.Prize.y) {
+		bound = min(bound, m.Prize.y/m.Buttons[i].y)
+	}
+	return bound
+}
+
+// solveLast fills in the cheapest presses of the pivots and the last free
+// button to reach x, y, returning how many presses of the last button tie for
+// cheapest (capped at 2, as only whether it's unique matters)
+func (m Machine) solveLast(pivots []int, last int, x, y int, presses []int) (int, error) {
+	var f Button
+	lo, hi := 0, 0
+	if last != -1 {
+		f = m.Buttons[last]
+		hi = math.MaxInt
+		if f.limit > 0 {
+			hi = f.limit
+		}
+	}
+
+	// each pivot's presses are (a - b*t) / d
+	var a, b []int
+	var d int
+	if len(pivots) == 2 {
+		p, q := m.Buttons[pivots[0]], m.Buttons[pivots[1]]
+		d = p.x*q.y - q.x*p.y
+		a = []int{x*q.y - q.x*y, p.x*y - x*p.y}
+		b = []int{f.x*q.y - q.x*f.y, p.x*f.y - f.x*p.y}
+	} else {
+		p := m.Buttons[pivots[0]]
+		d = m.scale(p, p.x, p.y)
+		a = []int{m.scale(p, x, y)}
+		b = []int{m.scale(p, f.x, f.y)}
+	}
+
+	if d < 0 {
+		d = -d
+		for i := range a {
+			a[i], b[i] = -a[i], -b[i]
+		}
+	}
+
+	for i, pivot := range pivots {
+		// a - b*t >= 0
+		if !narrow(&lo, &hi, a[i], b[i]) {
+			return 0, nil
+		}
+
+		// a - b*t <= limit*d
+		if limit := m.Buttons[pivot].limit; limit > 0 {
+			if !narrow(&lo, &hi, -a[i]+limit*d, -b[i]) {
+				return 0, nil
+			}
+		}
+	}
+
+	if lo > hi {
+		return 0, nil
+	}
+
+	if d == 0 {
+		return 0, nil
+	}
+
+	// the pivots' presses are whole when t = residue (mod modulus)
+	residue, modulus := 0, 1
+	for i := range a {
+		r, m, ok := congruence(b[i], a[i], d)
+		if !ok {
+			return 0, nil
+		}
+		if residue, modulus, ok = combine(residue, modulus, r, m); !ok {
+			return 0, nil
+		}
+	}
+
+	// d times the change in tokens for each press of the last button
+	slope := f.cost * d
+	for i, pivot := range pivots {
+		slope -= m.Buttons[pivot].cost * b[i]
+	}
+
+	if hi == math.MaxInt && slope < 0 {
+		return 0, fmt.Errorf("pressing button %s more always costs less", f.name)
+	}
+
+	first := lo + mod(residue-lo, modulus)
+	if first > hi {
+		return 0, nil
+	}
+
+	t, count := first, 1
+	if slope < 0 {
+		t = hi - mod(hi-residue, modulus)
+	}
+	if slope == 0 {
+		if hi == math.MaxInt {
+			count = 2
+		} else {
+			count = (hi-first)/modulus + 1
+		}
+	}
+
+	for i, pivot := range pivots {
+		presses[pivot] = (a[i] - b[i]*t) / d
+	}
+	if last != -1 {
+		presses[last] = t
+	}
+
+	return min(count, 2), nil
+}
+
+func mod(a, m int) int {
+	return (a%m + m) % m
+}
+
+// congruence solves b*t = a (mod d) for d > 0, as t = r (mod m), or false if
+// there's no t
+func congruence(b, a, d int) (r int, m int, ok bool) {
+	g := lib.GCD(b, d)
+	if g == 0 || a%g != 0 {
+		return 0, 0, false
+	}
+
+	m = d / g
+	if m == 1 {
+		return 0, 1, true
+	}
+	return mod(mod(a/g, m)*inverse(mod(b/g, m), m), m), m, true
+}
+
+// inverse is x where a*x = 1 (mod m), for a coprime to m
+func inverse(a, m int) int {
+	// extended Euclid, keeping only the coefficients of a
+	x, nextX := 0, 1
+	r, nextR := m, a
+	for nextR != 0 {
+		q := r / nextR
+		x, nextX = nextX, x-q*nextX
+		r, nextR = nextR, r-q*nextR
+	}
+	return mod(x, m)
+}
 
-	result := [2]float64{
-		inverse[0][0]*float64(m.Prize.x) + inverse[0][1]*float64(m.Prize.y),
-		inverse[1][0]*float64(m.Prize.x) + inverse[1][1]*float64(m.Prize.y),
+// combine finds the t where t = r1 (mod m1) and t = r2 (mod m2), as
+// t = r (mod lcm(m1, m2)), or false if there's no t
+func combine(r1, m1, r2, m2 int) (int, int, bool) {
+	// t = r1 + m1*k, where m1*k = r2 - r1 (mod m2)
+	k, m, ok := congruence(m1, r2-r1, m2)
+	if !ok {
+		return 0, 0, false
 	}
+	return mod(r1+m1*k, m1*m), m1 * m, true
+}
 
-	if IsWholeNumber(result[0]) && IsWholeNumber(result[1]) {
-		return int(math.Round(result[0]))*3 + int(math.Round(result[1]))
+// narrow shrinks lo..hi to the t where a - b*t >= 0, returning false if there
+// are none
+func narrow(lo, hi *int, a, b int) bool {
+	switch {
+	case b > 0:
+		*hi = min(*hi, floorDiv(a, b))
+	case b < 0:
+		*lo = max(*lo, ceilDiv(a, b))
+	default:
+		return a >= 0
 	}
+	return *lo <= *hi
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
 
-	return 0
+func ceilDiv(a, b int) int {
+	return -floorDiv(-a, b)
 }
 
-var BUTTON_REGEX = regexp.MustCompile(`X\+(\d+), Y\+(\d+)`)
-var PRIZE_REGEX = regexp.MustCompile(`X=(\d+), Y=(\d+)`)
+// MinTokensForPrize is the fewest tokens to win the prize, or 0 if it can't be
+// won
+func (m Machine) MinTokensForPrize() int {
+	solution, err := m.Solve()
+	if err != nil {
+		return 0
+	}
+	return solution.Tokens
+}
 
-func ParseMachines(content string) ([]Machine, error) {
+var BUTTON_REGEX = regexp.MustCompile(`^Button (\w+): X([+-]\d+), Y([+-]\d+)((?:, \w+=\d+)*)$`)
+var OPTION_REGEX = regexp.MustCompile(`, (\w+)=(\d+)`)
+var PRIZE_REGEX = regexp.MustCompile(`^Prize: X=(-?\d+), Y=(-?\d+)$`)
+
+// ParseMachines reads the machines, pricing each button from costs. A button
+// can also set its own cost and limit, e.g.
+//
+//	Button C: X+5, Y+7, Cost=2, Limit=50
+func ParseMachines(content string, costs Costs) ([]Machine, error) {
 	content = strings.TrimRight(content, "\n")
 
 	parts := strings.Split(content, "\n\n")
@@ -99,46 +499,50 @@ func ParseMachines(content string) ([]Machine, error) {
 
 	for i, part := range parts {
 		lines := strings.Split(part, "\n")
-		if len(lines) != 3 {
-			return nil, fmt.Errorf("want 3 lines of machine configuration, got %d", len(lines))
+		if len(lines) < 2 {
+			return nil, fmt.Errorf("want buttons and a prize for machine %d, got %d lines", i, len(lines))
 		}
 
 		machine := Machine{}
 
-		{
-			button := BUTTON_REGEX.FindStringSubmatch(lines[0])
-			if len(button) != 3 {
-				return nil, fmt.Errorf("machine %d; button %s; want 2 matches, got %s", i, lines[0], button)
-			}
-			x, err := strconv.Atoi(button[1])
-			if err != nil {
-				return nil, fmt.Errorf("machine %d; button %s; invalid Button A X position %s: %w", i, lines[0], button[1], err)
+		for _, line := range lines[:len(lines)-1] {
+			button := BUTTON_REGEX.FindStringSubmatch(line)
+			if len(button) != 5 {
+				return nil, fmt.Errorf("machine %d; button %s; want 4 matches, got %s", i, line, button)
 			}
-			y, err := strconv.Atoi(button[2])
-			if err != nil {
-				return nil, fmt.Errorf("machine %d; button %s; invalid Button A Y position %s: %w", i, lines[0], button[2], err)
+			cost, ok := costs[button[1]]
+			limit := 0
+			for _, option := range OPTION_REGEX.FindAllStringSubmatch(button[4], -1) {
+				value, err := strconv.Atoi(option[2])
+				if err != nil {
+					return nil, fmt.Errorf("machine %d; button %s; invalid %s %s: %w", i, line, option[1], option[2], err)
+				}
+				switch option[1] {
+				case "Cost":
+					cost, ok = value, true
+				case "Limit":
+					limit = value
+				default:
+					return nil, fmt.Errorf("machine %d; button %s; unknown option %s", i, line, option[1])
+				}
 			}
-			machine.A = Button{x, y, 3}
-		}
-
-		{
-			button := BUTTON_REGEX.FindStringSubmatch(lines[1])
-			if len(button) != 3 {
-				return nil, fmt.Errorf("machine %d; want 2 matches, got %s", i, button)
+			if !ok {
+				return nil, fmt.Errorf("machine %d; button %s; no cost for button %s", i, line, button[1])
 			}
-			x, err := strconv.Atoi(button[1])
+			x, err := strconv.Atoi(button[2])
 			if err != nil {
-				return nil, fmt.Errorf("machine %d; invalid Button B X position %s: %w", i, button[1], err)
+				return nil, fmt.Errorf("machine %d; button %s; invalid X position %s: %w", i, line, button[2], err)
 			}
-			y, err := strconv.Atoi(button[2])
+			y, err := strconv.Atoi(button[3])
 			if err != nil {
-				return nil, fmt.Errorf("machine %d; invalid Button B Y position %s: %w", i, button[2], err)
+				return nil, fmt.Errorf("machine %d; button %s; invalid Y position %s: %w", i, line, button[3], err)
 			}
-			machine.B = Button{x, y, 1}
+			machine.Buttons = append(machine.Buttons, Button{name: button[1], x: x, y: y, cost: cost, limit: limit})
 		}
 
 		{
-			prize := PRIZE_REGEX.FindStringSubmatch(lines[2])
+			line := lines[len(lines)-1]
+			prize := PRIZE_REGEX.FindStringSubmatch(line)
 			if len(prize) != 3 {
 				return nil, fmt.Errorf("machine %d prize; want 2 matches, got %s", i, prize)
 			}
@@ -160,13 +564,13 @@ func ParseMachines(content string) ([]Machine, error) {
 
 func Part1(content string) (int, error) {
 	total := 0
-	machines, err := ParseMachines(content)
+	machines, err := ParseMachines(content, DefaultCosts)
 	if err != nil {
 		return 0, fmt.Errorf("machine parsing: %w", err)
 	}
 
 	for _, machine := range machines {
-		total += machine.MinTokensForPrize()
+		total += machine.Limit(100).MinTokensForPrize()
 	}
 
 	return total, nil
@@ -174,15 +578,13 @@ func Part1(content string) (int, error) {
 
 func Part2(content string) (int, error) {
 	total := 0
-	machines, err := ParseMachines(content)
+	machines, err := ParseMachines(content, DefaultCosts)
 	if err != nil {
 		return 0, fmt.Errorf("machine parsing: %w", err)
 	}
 
 	for _, machine := range machines {
-		machine.Prize.x += 10000000000000
-		machine.Prize.y += 10000000000000
-		total += machine.MinTokensForPrize()
+		total += machine.Offset(PrizeOffset).MinTokensForPrize()
 	}
 
 	return total, nil
@@ -1,29 +1,282 @@
 package main
 
 import (
+	"errors"
+	"math/rand/v2"
+	"slices"
 	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/max-nicholson/advent-of-code-2024/lib/testutil"
 )
 
-func TestPart1(t *testing.T) {
-	result, err := Part1(`Button A: X+94, Y+34
+func TestExamples(t *testing.T) {
+	testutil.Golden(t, map[string]testutil.Part{
+		"part1": testutil.Content(Part1),
+		"part2": testutil.Content(Part2),
+	})
+}
+
+// bruteForce tries every combination of presses up to each button's limit
+func bruteForce(m Machine) (tokens int, optima int) {
+	tokens = -1
+	presses := make([]int, len(m.Buttons))
+
+	var try func(i, x, y, cost int)
+	try = func(i, x, y, cost int) {
+		if i == len(m.Buttons) {
+			if x != m.Prize.x || y != m.Prize.y {
+				return
+			}
+			switch {
+			case tokens == -1 || cost < tokens:
+				tokens, optima = cost, 1
+			case cost == tokens:
+				optima++
+			}
+			return
+		}
+
+		b := m.Buttons[i]
+		for n := 0; n <= b.limit; n++ {
+			presses[i] = n
+			try(i+1, x+n*b.x, y+n*b.y, cost+n*b.cost)
+		}
+	}
+
+	try(0, 0, 0, 0)
+	return tokens, optima
+}
+
+func TestSolveMatchesBruteForce(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 1))
+
+	for i := range 2000 {
+		m := Machine{}
+		for j := range 2 + r.IntN(3) {
+			b := Button{
+				name:  string(rune('A' + j)),
+				x:     r.IntN(9) - 2,
+				y:     r.IntN(9) - 2,
+				cost:  r.IntN(4),
+				limit: 1 + r.IntN(8),
+			}
+			// plenty of parallel buttons
+			if j > 0 && r.IntN(4) == 0 {
+				k := 1 + r.IntN(2)
+				b.x, b.y = m.Buttons[0].x*k, m.Buttons[0].y*k
+			}
+			m.Buttons = append(m.Buttons, b)
+		}
+
+		for j, b := range m.Buttons {
+			n := r.IntN(b.limit + 1)
+			if j == 0 && r.IntN(5) == 0 {
+				n += 3
+			}
+			m.Prize.x += n * b.x
+			m.Prize.y += n * b.y
+		}
+
+		tokens, optima := bruteForce(m)
+
+		solution, err := m.Solve()
+		if tokens == -1 {
+			if !errors.Is(err, ErrNoPrize) {
+				t.Fatalf("machine %d %+v: got %+v %v, want no prize", i, m, solution, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("machine %d %+v: %v", i, m, err)
+		}
+
+		if solution.Tokens != tokens || solution.Unique != (optima == 1) {
+			t.Fatalf("machine %d %+v: got %+v, want %d tokens with %d optima", i, m, solution, tokens, optima)
+		}
+
+		x, y, cost := 0, 0, 0
+		for j, n := range solution.Presses {
+			b := m.Buttons[j]
+			if n < 0 || n > b.limit {
+				t.Fatalf("machine %d %+v: %d presses of %s is out of range", i, m, n, b.name)
+			}
+			x, y, cost = x+n*b.x, y+n*b.y, cost+n*b.cost
+		}
+		if (Prize{x, y}) != m.Prize || cost != solution.Tokens {
+			t.Fatalf("machine %d %+v: presses %v don't win the prize for %d tokens", i, m, solution.Presses, solution.Tokens)
+		}
+	}
+}
+
+func TestSolveThreeButtonsFarAway(t *testing.T) {
+	n, k := 1000000000000, 500000000000
+	m := Machine{
+		Buttons: []Button{
+			{name: "A", x: 94, y: 34, cost: 3},
+			{name: "B", x: 22, y: 67, cost: 1},
+			// the same as pressing A and B, for a token less
+			{name: "C", x: 116, y: 101, cost: 3},
+		},
+		Prize: Prize{94*n + 22*k, 34*n + 67*k},
+	}
+
+	solution, err := m.Solve()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Solution{Presses: []int{n - k, 0, k}, Tokens: 3 * n, Unique: true}
+	if diff := cmp.Diff(want, solution); diff != "" {
+		t.Errorf("Solve() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSolveWithoutLimits(t *testing.T) {
+	r := rand.New(rand.NewPCG(2, 2))
+
+	for i := range 500 {
+		m := Machine{}
+		for j := range 2 + r.IntN(2) {
+			m.Buttons = append(m.Buttons, Button{name: string(rune('A' + j)), x: 1 + r.IntN(6), y: 1 + r.IntN(6), cost: 1 + r.IntN(3)})
+		}
+		for _, b := range m.Buttons {
+			n := r.IntN(10)
+			m.Prize.x += n * b.x
+			m.Prize.y += n * b.y
+		}
+
+		// moving forwards, no button can be pressed more than it takes to pass the prize
+		limited := m.Limit(m.Prize.x)
+		tokens, optima := bruteForce(limited)
+
+		solution, err := m.Solve()
+		if err != nil {
+			t.Fatalf("machine %d %+v: %v", i, m, err)
+		}
+		if solution.Tokens != tokens || solution.Unique != (optima == 1) {
+			t.Fatalf("machine %d %+v: got %+v, want %d tokens with %d optima", i, m, solution, tokens, optima)
+		}
+	}
+}
+
+func TestParseMachinesWithOptions(t *testing.T) {
+	content := `Button A: X+94, Y+34
 Button B: X+22, Y+67
-Prize: X=8400, Y=5400
+Button C: X+116, Y+101, Cost=2, Limit=50
+Prize: X=8400, Y=5400`
+
+	machines, err := ParseMachines(content, Costs{"A": 3, "B": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Machine{{
+		Buttons: []Button{
+			{name: "A", x: 94, y: 34, cost: 3},
+			{name: "B", x: 22, y: 67, cost: 1},
+			{name: "C", x: 116, y: 101, cost: 2, limit: 50},
+		},
+		Prize: Prize{8400, 5400},
+	}}
+	if diff := cmp.Diff(want, machines, cmp.AllowUnexported(Button{}, Prize{})); diff != "" {
+		t.Errorf("ParseMachines() mismatch (-want +got):\n%s", diff)
+	}
+
+	// C is A and B together for 2 tokens less, so as many presses of A and B
+	// as possible (the example's 80 A and 40 B) become C instead
+	solution, err := machines[0].Solve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(Solution{Presses: []int{40, 0, 40}, Tokens: 200, Unique: true}, solution); diff != "" {
+		t.Errorf("Solve() mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := ParseMachines(content, Costs{"A": 3}); err == nil {
+		t.Errorf("ParseMachines() want error for B without a cost")
+	}
+	if _, err := ParseMachines("Button A: X+1, Y+1, Colour=2\nPrize: X=1, Y=1", DefaultCosts); err == nil {
+		t.Errorf("ParseMachines() want error for an unknown option")
+	}
+}
+
+func TestSolveFourButtonsFarAway(t *testing.T) {
+	m := Machine{
+		Buttons: []Button{
+			{name: "A", x: 94, y: 34, cost: 3},
+			{name: "B", x: 22, y: 67, cost: 1},
+			{name: "C", x: 116, y: 101, cost: 3},
+			{name: "D", x: 5, y: 7, cost: 1},
+		},
+		Prize: Prize{PrizeOffset + 8400, PrizeOffset + 5400},
+	}
 
-Button A: X+26, Y+66
-Button B: X+67, Y+21
-Prize: X=12748, Y=12176
+	done := make(chan error)
+	go func() {
+		_, err := m.Solve()
+		done <- err
+	}()
 
-Button A: X+17, Y+86
-Button B: X+84, Y+37
-Prize: X=7870, Y=6450
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrSearchTooLarge) {
+			t.Errorf("Solve() = %v, want ErrSearchTooLarge", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Solve() still searching after 5s")
+	}
 
-Button A: X+69, Y+23
-Button B: X+27, Y+71
-Prize: X=18641, Y=10279`)
+	// close by, all 4 buttons are still searched
+	m.Prize = Prize{94 + 22 + 116 + 5*3, 34 + 67 + 101 + 7*3}
+	solution, err := m.Solve()
 	if err != nil {
 		t.Fatal(err)
 	}
-	if result != 480 {
-		t.Fatalf("expected 480, got %d", result)
+	if tokens, _ := bruteForce(m.Limit(100)); solution.Tokens != tokens {
+		t.Errorf("Solve() = %+v, want %d tokens", solution, tokens)
+	}
+}
+
+func TestCongruenceMatchesScan(t *testing.T) {
+	for d := 1; d <= 30; d++ {
+		for b := -40; b <= 40; b++ {
+			for a := -40; a <= 40; a++ {
+				var want []int
+				for n := range d {
+					if (b*n-a)%d == 0 {
+						want = append(want, n)
+					}
+				}
+
+				r, m, ok := congruence(b, a, d)
+				var got []int
+				for n := range d {
+					if ok && mod(n-r, m) == 0 {
+						got = append(got, n)
+					}
+				}
+				if !slices.Equal(got, want) {
+					t.Fatalf("congruence(%d, %d, %d) = %d mod %d, %t; want t in %v", b, a, d, r, m, ok, want)
+				}
+			}
+		}
+	}
+
+	for m1 := 1; m1 <= 12; m1++ {
+		for m2 := 1; m2 <= 12; m2++ {
+			for r1 := range m1 {
+				for r2 := range m2 {
+					r, m, ok := combine(r1, m1, r2, m2)
+					for n := range m1 * m2 {
+						want := n%m1 == r1 && n%m2 == r2
+						if got := ok && mod(n-r, m) == 0; got != want {
+							t.Fatalf("combine(%d, %d, %d, %d) = %d mod %d, %t; wrong for %d", r1, m1, r2, m2, r, m, ok, n)
+						}
+					}
+				}
+			}
+		}
 	}
 }
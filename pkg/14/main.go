@@ -1,16 +1,30 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"image/color"
 	"log"
+	"math"
+	"os"
 	"regexp"
 	"strconv"
-	"strings"
 
 	"github.com/max-nicholson/advent-of-code-2024/lib"
+	"github.com/max-nicholson/advent-of-code-2024/lib/render"
 )
 
+var renderPath = flag.String("render", "", "Render the robots moving until they form the picture to the terminal (-), a .gif or a .png")
+var detectorName = flag.String("detector", "overlap", "How to spot the picture: overlap, variance, entropy or component")
+
 func main() {
+	flag.Parse()
+
+	detector, ok := Detectors[*detectorName]
+	if !ok {
+		log.Fatalf("unknown detector %s", *detectorName)
+	}
+
 	lines, err := lib.ReadLines("pkg/14/input.txt")
 	if err != nil {
 		log.Fatal(err)
@@ -22,11 +36,36 @@ func main() {
 	}
 	fmt.Printf("part1: %d\n", part1)
 
-	part2, err := Part2(lines, 101, 103)
+	robots, err := ParseRobots(lines)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	part2, frame, err := Search(robots, 101, 103, detector)
 	if err != nil {
 		log.Fatal(err)
 	}
 	fmt.Printf("part2: %d\n", part2)
+
+	if *renderPath == "" {
+		if err := render.ANSI(os.Stdout, frame, palette); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	recorder, err := render.Open(*renderPath, palette)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := Render(robots, 101, 103, part2, recorder); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := recorder.Close(); err != nil {
+		log.Fatal(err)
+	}
 }
 
 type Point struct {
@@ -72,82 +111,271 @@ func ParseRobots(lines []string) ([]Robot, error) {
 	return robots, nil
 }
 
-func Part1(lines []string, width int, height int) (int, error) {
-	if width%2 == 0 || height%2 == 0 {
-		return 0, fmt.Errorf("invalid width/height; must be odd")
+// Step moves every robot forward by one second, wrapping around the edges
+func Step(robots []Robot, width int, height int) {
+	for i, robot := range robots {
+		robots[i].position = Point{
+			((robot.position.x+robot.velocity.x)%width + width) % width,
+			((robot.position.y+robot.velocity.y)%height + height) % height,
+		}
 	}
+}
 
-	robots, err := ParseRobots(lines)
-	if err != nil {
-		return 0, fmt.Errorf("robot parsing: %w", err)
+var palette = render.Palette{
+	Colours: map[rune]color.RGBA{
+		'#': {0, 200, 0, 255},
+	},
+	Default: color.RGBA{0, 0, 0, 255},
+}
+
+// Draw marks every tile with at least one robot on it with #
+func Draw(robots []Robot, width int, height int) render.Runes {
+	frame := render.Blank(width, height, ' ')
+	for _, robot := range robots {
+		frame[robot.position.y][robot.position.x] = '#'
 	}
+	return frame
+}
 
-	const duration = 100
+// Render draws the robots at every second from now until seconds have passed
+func Render(robots []Robot, width int, height int, seconds int, sink render.Sink) error {
+	for second := 0; ; second++ {
+		if err := sink.Frame(Draw(robots, width, height)); err != nil {
+			return err
+		}
 
-	quadrants := [2][2]int{
-		{0, 0},
-		{0, 0},
+		if second == seconds {
+			return nil
+		}
+
+		Step(robots, width, height)
 	}
+}
 
-	for _, robot := range robots {
-		visited := map[Point]int{
-			robot.position: 0,
-		}
-		current := robot.position
+// At is where the robot is after t seconds, wrapping around the edges
+func (r Robot) At(t int, width int, height int) Point {
+	return Point{
+		wrap(r.position.x+wrap(r.velocity.x, width)*wrap(t, width), width),
+		wrap(r.position.y+wrap(r.velocity.y, height)*wrap(t, height), height),
+	}
+}
 
-		for seconds := 1; seconds <= duration; seconds++ {
-			next := Point{
-				current.x + robot.velocity.x,
-				current.y + robot.velocity.y,
-			}
+func wrap(n, size int) int {
+	return (n%size + size) % size
+}
+
+// Positions moves every robot forward t seconds without stepping through the
+// seconds in between
+func Positions(robots []Robot, t int, width int, height int) []Robot {
+	moved := make([]Robot, len(robots))
+	for i, robot := range robots {
+		moved[i] = Robot{position: robot.At(t, width, height), velocity: robot.velocity}
+	}
+	return moved
+}
+
+// Detector finds the time the robots are most likely to be drawing a picture.
+// Each robot is back where it started after width*height seconds, so there's
+// only one period to search
+type Detector interface {
+	Detect(robots []Robot, width int, height int) (int, error)
+}
 
-			// wrap position
-			// assumption that no robot has a velocity greater than the width/height of the grid
-			// as we'd need more complicated wrapping
-			if next.x < 0 {
-				next.x += width
-			} else if next.x >= width {
-				next.x -= width
+// NoOverlap is the first time no two robots share a tile
+type NoOverlap struct{}
+
+func (NoOverlap) Detect(robots []Robot, width int, height int) (int, error) {
+	occupied := make([]int, width*height)
+	for t := 1; t <= width*height; t++ {
+		overlap := false
+		for _, robot := range robots {
+			p := robot.At(t, width, height)
+			if occupied[p.y*width+p.x] == t {
+				overlap = true
+				break
 			}
+			occupied[p.y*width+p.x] = t
+		}
+
+		if !overlap {
+			return t, nil
+		}
+	}
+
+	return 0, fmt.Errorf("robots always overlap")
+}
 
-			if next.y < 0 {
-				next.y += height
-			} else if next.y >= height {
-				next.y -= height
+// Measure scores how spread out the robots are along one axis, lower being
+// more bunched up
+type Measure func(values []int, size int) float64
+
+func Variance(values []int, size int) float64 {
+	var sum, squares float64
+	for _, v := range values {
+		sum += float64(v)
+		squares += float64(v * v)
+	}
+	mean := sum / float64(len(values))
+	return squares/float64(len(values)) - mean*mean
+}
+
+// Entropy is the Shannon entropy of how many robots are in each row or column
+func Entropy(values []int, size int) float64 {
+	counts := make([]int, size)
+	for _, v := range values {
+		counts[v]++
+	}
+
+	var entropy float64
+	for _, count := range counts {
+		if count > 0 {
+			p := float64(count) / float64(len(values))
+			entropy -= p * math.Log2(p)
+		}
+	}
+	return entropy
+}
+
+// Clustered is the time the robots are most bunched up. x positions repeat
+// every width seconds and y every height, so each axis is searched on its own
+// and the two times are combined with the Chinese remainder theorem
+type Clustered struct {
+	Measure Measure
+}
+
+func (c Clustered) Detect(robots []Robot, width int, height int) (int, error) {
+	if lib.GCD(width, height) != 1 {
+		return 0, fmt.Errorf("width %d and height %d must be coprime", width, height)
+	}
+
+	best := func(size int, axis func(Robot, int) int) int {
+		bestT, bestScore := 0, math.Inf(1)
+		values := make([]int, len(robots))
+		for t := range size {
+			for i, robot := range robots {
+				values[i] = axis(robot, t)
+			}
+			if score := c.Measure(values, size); score < bestScore {
+				bestT, bestScore = t, score
 			}
+		}
+		return bestT
+	}
 
-			if _, ok := visited[next]; ok {
-				// determine cycle length, and therefore final position
-				cycle := seconds
+	tx := best(width, func(r Robot, t int) int { return r.At(t, width, height).x })
+	ty := best(height, func(r Robot, t int) int { return r.At(t, width, height).y })
 
-				left := duration - seconds
+	// t = tx + width*k, where tx + width*k = ty (mod height)
+	k := wrap((ty-tx)*inverse(width, height), height)
+	t := tx + width*k
+	if t == 0 {
+		t = width * height
+	}
+	return t, nil
+}
 
-				r := left % cycle
-				if r == 0 {
-					current = next
-					break
-				}
+// inverse is the modular inverse of a mod m, for coprime a and m
+func inverse(a, m int) int {
+	// extended Euclid, tracking the coefficient of a
+	r0, r1 := wrap(a, m), m
+	s0, s1 := 1, 0
+	for r1 != 0 {
+		q := r0 / r1
+		r0, r1 = r1, r0-q*r1
+		s0, s1 = s1, s0-q*s1
+	}
+	return wrap(s0, m)
+}
+
+// LargestComponent is the time with the biggest group of robots on touching
+// tiles
+type LargestComponent struct{}
+
+func (LargestComponent) Detect(robots []Robot, width int, height int) (int, error) {
+	occupied := make([]int, width*height)
+	visited := make([]int, width*height)
+	stack := make([]int, 0, len(robots))
+
+	bestT, bestSize := 0, 0
+	for t := 1; t <= width*height; t++ {
+		for _, robot := range robots {
+			p := robot.At(t, width, height)
+			occupied[p.y*width+p.x] = t
+		}
 
-				var found bool
-				for p, s := range visited {
-					if s == r {
-						found = true
-						current = p
-						break
+		for _, robot := range robots {
+			p := robot.At(t, width, height)
+			start := p.y*width + p.x
+			if visited[start] == t {
+				continue
+			}
+
+			visited[start] = t
+			stack = append(stack[:0], start)
+			size := 0
+			for len(stack) > 0 {
+				i := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				size++
+
+				x, y := i%width, i/width
+				for _, n := range [][2]int{{x - 1, y}, {x + 1, y}, {x, y - 1}, {x, y + 1}} {
+					if n[0] < 0 || n[0] >= width || n[1] < 0 || n[1] >= height {
+						continue
+					}
+					j := n[1]*width + n[0]
+					if occupied[j] == t && visited[j] != t {
+						visited[j] = t
+						stack = append(stack, j)
 					}
 				}
-				if !found {
-					panic("unable to find final point from cycle")
-				} else {
-					break
-				}
+			}
 
+			if size > bestSize {
+				bestT, bestSize = t, size
 			}
+		}
+	}
 
-			visited[next] = seconds
+	return bestT, nil
+}
 
-			current = next
-		}
+var Detectors = map[string]Detector{
+	"overlap":   NoOverlap{},
+	"variance":  Clustered{Measure: Variance},
+	"entropy":   Clustered{Measure: Entropy},
+	"component": LargestComponent{},
+}
+
+// Search runs the detector, and draws the robots at the time it picks
+func Search(robots []Robot, width int, height int, detector Detector) (int, render.Runes, error) {
+	t, err := detector.Detect(robots, width, height)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return t, Draw(Positions(robots, t, width, height), width, height), nil
+}
+
+func Part1(lines []string, width int, height int) (int, error) {
+	if width%2 == 0 || height%2 == 0 {
+		return 0, fmt.Errorf("invalid width/height; must be odd")
+	}
+
+	robots, err := ParseRobots(lines)
+	if err != nil {
+		return 0, fmt.Errorf("robot parsing: %w", err)
+	}
+
+	const duration = 100
+
+	quadrants := [2][2]int{
+		{0, 0},
+		{0, 0},
+	}
+
+	for _, robot := range robots {
+		current := robot.At(duration, width, height)
 
 		// what quadrant are we in
 		if current.x == width/2 || current.y == height/2 {
@@ -174,65 +402,11 @@ func Part1(lines []string, width int, height int) (int, error) {
 	return quadrants[0][0] * quadrants[0][1] * quadrants[1][0] * quadrants[1][1], nil
 }
 
-func Part2(lines []string, width int, height int) (int, error) {
+func Part2(lines []string, width int, height int, detector Detector) (int, error) {
 	robots, err := ParseRobots(lines)
 	if err != nil {
 		return 0, fmt.Errorf("robot parsing: %w", err)
 	}
 
-	for seconds := 1; ; seconds++ {
-		current := map[Point]struct{}{}
-		var overlap bool = false
-
-		for i, robot := range robots {
-			next := Point{
-				robot.position.x,
-				robot.position.y,
-			}
-			next.x += robot.velocity.x
-			next.y += robot.velocity.y
-
-			// wrap position
-			// assumption that no robot has a velocity greater than the width/height of the grid
-			// as we'd need more complicated wrapping
-			if next.x < 0 {
-				next.x += width
-			} else if next.x >= width {
-				next.x -= width
-			}
-
-			if next.y < 0 {
-				next.y += height
-			} else if next.y >= height {
-				next.y -= height
-			}
-
-			if _, ok := current[next]; ok {
-				overlap = true
-			} else {
-				current[next] = struct{}{}
-			}
-
-			robot.position = next
-			robots[i] = robot
-		}
-
-		if !overlap {
-			var b strings.Builder
-			b.Grow(height * width)
-			for y := range height {
-				for x := range width {
-					p := Point{x, y}
-					if _, ok := current[p]; ok {
-						b.WriteRune('.')
-					} else {
-						b.WriteRune(' ')
-					}
-				}
-				b.WriteRune('\n')
-			}
-			fmt.Println(b.String())
-			return seconds, nil
-		}
-	}
+	return detector.Detect(robots, width, height)
 }
@@ -1,28 +1,103 @@
 package main
 
 import (
-	"strings"
+	"maps"
+	"math/rand/v2"
+	"slices"
 	"testing"
+
+	"github.com/max-nicholson/advent-of-code-2024/lib/gen"
+	"github.com/max-nicholson/advent-of-code-2024/lib/testutil"
 )
 
-func TestPart1(t *testing.T) {
-	result, err := Part1(strings.Split(`p=0,4 v=3,-3
-p=6,3 v=-1,-3
-p=10,3 v=-1,2
-p=2,0 v=2,-1
-p=0,0 v=1,3
-p=3,0 v=-2,-2
-p=7,6 v=-1,-3
-p=3,0 v=-1,-2
-p=9,3 v=2,3
-p=7,3 v=-1,2
-p=2,4 v=2,-3
-p=9,5 v=-3,-3`, "\n"), 11, 7)
+func TestExamples(t *testing.T) {
+	testutil.Golden(t, map[string]testutil.Part{
+		"part1": testutil.Lines(func(lines []string) (int, error) {
+			return Part1(lines, 11, 7)
+		}),
+	})
+}
+
+func TestAtMatchesStep(t *testing.T) {
+	robots, err := ParseRobots([]string{
+		"p=0,4 v=3,-3",
+		"p=6,3 v=-1,-3",
+		"p=2,4 v=2,-3",
+		"p=100,102 v=-99,99",
+		"p=50,0 v=-1234,5678",
+	})
 	if err != nil {
 		t.Fatal(err)
 	}
-	if result != 12 {
-		t.Fatalf("expected 12, got %d", result)
+
+	stepped := make([]Robot, len(robots))
+	copy(stepped, robots)
+	for second := range 500 {
+		for i, robot := range robots {
+			if got := robot.At(second, gen.Day14Width, gen.Day14Height); got != stepped[i].position {
+				t.Fatalf("At(%d) = %v, want %v", second, got, stepped[i].position)
+			}
+		}
+		Step(stepped, gen.Day14Width, gen.Day14Height)
+	}
+}
+
+// picture plants a filled square of robots at time t, among noise robots
+// scattered randomly
+func picture(r *rand.Rand, t int, noise int) []Robot {
+	var robots []Robot
+	place := func(x, y int) {
+		v := Point{r.IntN(199) - 99, r.IntN(199) - 99}
+		rewound := Robot{position: Point{x, y}, velocity: v}.At(-t, gen.Day14Width, gen.Day14Height)
+		robots = append(robots, Robot{position: rewound, velocity: v})
+	}
+
+	for y := 40; y < 60; y++ {
+		for x := 30; x < 50; x++ {
+			place(x, y)
+		}
+	}
+	for _, tile := range r.Perm(gen.Day14Width * gen.Day14Height)[:noise] {
+		x, y := tile%gen.Day14Width, tile/gen.Day14Width
+		if x >= 30 && x < 50 && y >= 40 && y < 60 {
+			continue
+		}
+		place(x, y)
 	}
+	return robots
 }
 
+func TestDetectors(t *testing.T) {
+	for i, name := range slices.Sorted(maps.Keys(Detectors)) {
+		detector := Detectors[name]
+		t.Run(name, func(t *testing.T) {
+			// seeded per detector, so a subtest picked with -run gets the
+			// same picture as in a full run
+			r := rand.New(rand.NewPCG(14, uint64(i)))
+			want := 1 + r.IntN(gen.Day14Width*gen.Day14Height-1)
+			robots := picture(r, want, 200)
+
+			got, frame, err := Search(robots, gen.Day14Width, gen.Day14Height, detector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Fatalf("Search() = %d, want %d", got, want)
+			}
+			if frame[50][40] != '#' {
+				t.Errorf("Search() frame is missing the picture")
+			}
+		})
+	}
+}
+
+func TestClusteredNeedsCoprimeDimensions(t *testing.T) {
+	robots, err := ParseRobots([]string{"p=0,0 v=1,1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := (Clustered{Measure: Variance}).Detect(robots, 10, 4); err == nil {
+		t.Errorf("Detect() want error for 10x4")
+	}
+}
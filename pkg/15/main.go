@@ -1,15 +1,24 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"image/color"
+	"iter"
 	"log"
 	"slices"
 	"strings"
+	"unicode"
 
 	"github.com/max-nicholson/advent-of-code-2024/lib"
+	"github.com/max-nicholson/advent-of-code-2024/lib/render"
 )
 
+var renderPath = flag.String("render", "", "Render the robot moving boxes around the expanded warehouse to the terminal (-), a .gif or a .png")
+
 func main() {
+	flag.Parse()
+
 	input, err := lib.ReadFile("pkg/15/input.txt")
 	if err != nil {
 		log.Fatal(err)
@@ -26,10 +35,28 @@ func main() {
 		log.Fatal(err)
 	}
 	fmt.Printf("part2: %d\n", part2)
+
+	if *renderPath != "" {
+		recorder, err := render.Open(*renderPath, palette)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if _, err := Simulate(input, true, recorder); err != nil {
+			log.Fatal(err)
+		}
+
+		if err := recorder.Close(); err != nil {
+			log.Fatal(err)
+		}
+	}
 }
 
 func ParseInput(input string) ([][]rune, []Point, error) {
 	parts := strings.Split(input, "\n\n")
+	if len(parts) != 2 {
+		return nil, nil, fmt.Errorf("want a warehouse and movements separated by a blank line")
+	}
 
 	warehouse := [][]rune{}
 	lines := strings.Split(parts[0], "\n")
@@ -69,281 +96,435 @@ type Point struct {
 	c int
 }
 
-func FindRobot(warehouse [][]rune) (Point, bool) {
-	for r, row := range warehouse {
-		for c, coordinate := range row {
-			if coordinate == '@' {
-				return Point{r, c}, true
+func (p Point) Add(q Point) Point {
+	return Point{p.r + q.r, p.c + q.c}
+}
+
+func (p Point) Sub(q Point) Point {
+	return Point{p.r - q.r, p.c - q.c}
+}
+
+// Shape is the tiles a box covers, as offsets from its top left, and what to
+// draw on each of them
+type Shape struct {
+	Offsets []Point
+	Glyphs  []rune
+}
+
+var (
+	Single = Shape{Offsets: []Point{{0, 0}}, Glyphs: []rune{'O'}}
+	Wide   = Shape{Offsets: []Point{{0, 0}, {0, 1}}, Glyphs: []rune{'[', ']'}}
+)
+
+type Box struct {
+	ID    int
+	At    Point
+	Shape Shape
+}
+
+// Cells is every tile the box covers, and its glyph
+func (b Box) Cells() iter.Seq2[Point, rune] {
+	return func(yield func(Point, rune) bool) {
+		for i, offset := range b.Shape.Offsets {
+			if !yield(b.At.Add(offset), b.Shape.Glyphs[i]) {
+				return
 			}
 		}
 	}
-
-	return Point{}, false
 }
 
-func Part1(input string) (int, error) {
-	warehouse, movements, err := ParseInput(input)
-	if err != nil {
-		return 0, err
+// GPS is 100 times the distance from the top edge of the map to the box's
+// closest edge, plus the distance from the left edge to its closest edge
+func (b Box) GPS() int {
+	top, left := b.At.r, b.At.c
+	for cell := range b.Cells() {
+		top, left = min(top, cell.r), min(left, cell.c)
 	}
+	return 100*top + left
+}
+
+// Push is one of the robot's moves, and the boxes it pushed along
+type Push struct {
+	Move  Point
+	From  Point
+	Moved bool
+	Boxes []int
+}
+
+// Warehouse is the robot, the walls and the boxes. Boxes can be any shape; a
+// move either pushes every box in the way or, if any of them would hit a wall,
+// nothing at all
+type Warehouse struct {
+	rows     int
+	columns  int
+	walls    []bool
+	occupant []int // box ID + 1, or 0 when empty
+	boxes    []Box
+	robot    Point
+	history  []Push
+}
 
-	current, found := FindRobot(warehouse)
-	if !found {
-		return 0, fmt.Errorf("could not find robot @ in warehouse %v", warehouse)
+// NewWarehouse reads the warehouse's walls (#), empty space (.), the robot (@)
+// and its boxes: O for a single tile, [] for a wide box, and any other letter
+// for a box made up of all the touching tiles with that letter
+func NewWarehouse(grid [][]rune) (*Warehouse, error) {
+	w := &Warehouse{rows: len(grid)}
+	for _, row := range grid {
+		w.columns = max(w.columns, len(row))
+	}
+	w.walls = make([]bool, w.rows*w.columns)
+	w.occupant = make([]int, w.rows*w.columns)
+	// anything past the end of a short row is wall, as it is for at
+	for r, row := range grid {
+		for c := len(row); c < w.columns; c++ {
+			w.walls[w.index(Point{r, c})] = true
+		}
 	}
 
-	for i, move := range movements {
-		next := Point{
-			current.r + move.r,
-			current.c + move.c,
+	at := func(p Point) rune {
+		if p.r < 0 || p.r >= len(grid) || p.c < 0 || p.c >= len(grid[p.r]) {
+			return '#'
 		}
+		return grid[p.r][p.c]
+	}
 
-		switch warehouse[next.r][next.c] {
-		case '@':
-			return 0, fmt.Errorf("invalid move at index %d, robot already at %v", next, i)
-		case '#':
-			// blocked
-		case '.':
-			warehouse[next.r][next.c] = '@'
-			warehouse[current.r][current.c] = '.'
-			current = next
-		case 'O':
-			end := Point{
-				next.r + move.r,
-				next.c + move.c,
-			}
-			for {
-				contents := warehouse[end.r][end.c]
-				if contents == '.' {
-					warehouse[next.r][next.c] = '@'
-					warehouse[current.r][current.c] = '.'
-					warehouse[end.r][end.c] = 'O'
-					current = next
-					break
-				} else if contents == '#' {
-					break
-				} else {
-					end.r += move.r
-					end.c += move.c
-				}
+	robots := 0
+	for r, row := range grid {
+		for c, tile := range row {
+			if tile == '@' {
+				w.robot = Point{r, c}
+				robots++
 			}
-		default:
-			return 0, fmt.Errorf("unexpected location %s at %v", string(warehouse[next.r][next.c]), next)
 		}
 	}
 
-	var sum int
+	if robots != 1 {
+		return nil, fmt.Errorf("want 1 robot @ in warehouse, found %d", robots)
+	}
 
-	for r, row := range warehouse {
-		for c, coordinate := range row {
-			if coordinate != 'O' {
+	for r, row := range grid {
+		for c, tile := range row {
+			p := Point{r, c}
+			if w.occupant[w.index(p)] != 0 {
+				continue
+			}
+
+			var shape Shape
+			switch {
+			case tile == '#':
+				w.walls[w.index(p)] = true
+				continue
+			case tile == '.' || tile == '@':
 				continue
+			case tile == 'O':
+				shape = Single
+			case tile == '[':
+				if at(p.Add(Point{c: 1})) != ']' {
+					return nil, fmt.Errorf("unmatched [ at row %d column %d", r, c)
+				}
+				shape = Wide
+			case tile == ']':
+				return nil, fmt.Errorf("unmatched ] at row %d column %d", r, c)
+			case unicode.IsLetter(tile):
+				shape = flood(at, p)
+			default:
+				return nil, fmt.Errorf("invalid tile %s at row %d column %d", string(tile), r, c)
 			}
 
-			sum += 100*r + c
+			if _, err := w.AddBox(shape, p); err != nil {
+				return nil, err
+			}
 		}
 	}
 
-	return sum, nil
+	return w, nil
 }
 
-func ExpandWarehouse(warehouse [][]rune) [][]rune {
-	expanded := make([][]rune, len(warehouse))
-
-	for r, row := range warehouse {
-		expanded[r] = make([]rune, len(row)*2)
-		for c, tile := range row {
-			switch tile {
-			case '#':
-				expanded[r][2*c] = '#'
-				expanded[r][2*c+1] = '#'
-			case 'O':
-				expanded[r][2*c] = '['
-				expanded[r][2*c+1] = ']'
-			case '.':
-				expanded[r][2*c] = '.'
-				expanded[r][2*c+1] = '.'
-			case '@':
-				expanded[r][2*c] = '@'
-				expanded[r][2*c+1] = '.'
-			default:
-				panic(fmt.Sprintf("invalid tile %s at row %d column %d", string(tile), r, c))
+// flood finds the shape of the letter box at start, from its touching tiles
+func flood(at func(Point) rune, start Point) Shape {
+	letter := at(start)
+	shape := Shape{}
+	seen := map[Point]bool{start: true}
+	queue := []Point{start}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		shape.Offsets = append(shape.Offsets, p.Sub(start))
+		shape.Glyphs = append(shape.Glyphs, letter)
+
+		for _, move := range []Point{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+			next := p.Add(move)
+			if !seen[next] && at(next) == letter {
+				seen[next] = true
+				queue = append(queue, next)
 			}
 		}
 	}
+	return shape
+}
+
+func (w *Warehouse) index(p Point) int {
+	return p.r*w.columns + p.c
+}
+
+func (w *Warehouse) inBounds(p Point) bool {
+	return p.r >= 0 && p.r < w.rows && p.c >= 0 && p.c < w.columns
+}
 
-	return expanded
+func (w *Warehouse) blocked(p Point) bool {
+	return !w.inBounds(p) || w.walls[w.index(p)]
 }
 
-func TryMoveBoxHorizontally(warehouse [][]rune, start Point, move Point) bool {
-	if move.r != 0 {
-		panic("attempting horizontal move with vertical delta")
+// AddBox places a box of the given shape with its top left at, returning its
+// ID, as long as none of the tiles it covers are taken
+func (w *Warehouse) AddBox(shape Shape, at Point) (int, error) {
+	box := Box{ID: len(w.boxes), At: at, Shape: shape}
+	for cell := range box.Cells() {
+		if w.blocked(cell) || w.occupant[w.index(cell)] != 0 || cell == w.robot {
+			return 0, fmt.Errorf("box %d can't be placed at %v", box.ID, cell)
+		}
 	}
 
-	box := Point{
-		r: start.r,
-		c: start.c + move.c,
+	w.boxes = append(w.boxes, box)
+	w.place(box.ID)
+	return box.ID, nil
+}
+
+func (w *Warehouse) place(id int) {
+	for cell := range w.boxes[id].Cells() {
+		w.occupant[w.index(cell)] = id + 1
 	}
-	step := Point{
-		c: move.c * 2,
+}
+
+func (w *Warehouse) lift(id int) {
+	for cell := range w.boxes[id].Cells() {
+		w.occupant[w.index(cell)] = 0
 	}
+}
 
-	next := Point{
-		start.r,
-		box.c + step.c,
+func (w *Warehouse) Robot() Point {
+	return w.robot
+}
+
+func (w *Warehouse) Boxes() []Box {
+	return slices.Clone(w.boxes)
+}
+
+func (w *Warehouse) BoxAt(p Point) (Box, bool) {
+	if !w.inBounds(p) || w.occupant[w.index(p)] == 0 {
+		return Box{}, false
 	}
+	return w.boxes[w.occupant[w.index(p)]-1], true
+}
+
+// pushes finds every box that moves if something pushes into from, or false if
+// any of them would hit a wall
+func (w *Warehouse) pushes(from Point, move Point) ([]int, bool) {
+	var pushed []int
+	seen := map[int]bool{}
+	queue := []Point{from}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+
+		if w.blocked(p) {
+			return nil, false
+		}
+
+		box, ok := w.BoxAt(p)
+		if !ok || seen[box.ID] {
+			continue
+		}
+		seen[box.ID] = true
+		pushed = append(pushed, box.ID)
 
-	if move.c == 1 && warehouse[box.r][box.c] != '[' {
-		panic(fmt.Sprintf("attempting move to right against %s; not left edge of a box", string(warehouse[box.r][box.c])))
+		for cell := range box.Cells() {
+			queue = append(queue, cell.Add(move))
+		}
 	}
+	return pushed, true
+}
 
-	if move.c == -1 && warehouse[box.r][box.c] != ']' {
-		panic(fmt.Sprintf("attempting move to left against %s; not right edge of a box", string(warehouse[box.r][box.c])))
+// shift moves the boxes along by move, all at once
+func (w *Warehouse) shift(boxes []int, move Point) {
+	for _, id := range boxes {
+		w.lift(id)
+	}
+	for _, id := range boxes {
+		w.boxes[id].At = w.boxes[id].At.Add(move)
+		w.place(id)
 	}
+}
 
-	var boxes int = 1
-	for {
-		tile := warehouse[next.r][next.c]
-		if tile == '.' {
-			// slide every box across by 1
-			// start with the space and work backwards
-			for i := 0; i < boxes*2; i++ {
-				warehouse[next.r][next.c-i*move.c] = warehouse[next.r][next.c-((i+1)*move.c)]
-			}
-			warehouse[start.r][box.c] = '@'
-			warehouse[start.r][start.c] = '.'
-			return true
-		} else if tile == '#' {
-			return false
-		} else {
-			boxes += 1
-			next.c += step.c
-		}
+// Move tries to move the robot, pushing any boxes in the way. Blocked moves
+// still count towards the history, so Undo steps back exactly one move
+func (w *Warehouse) Move(move Point) bool {
+	push := Push{Move: move, From: w.robot}
+	next := w.robot.Add(move)
+	if boxes, ok := w.pushes(next, move); ok {
+		w.shift(boxes, move)
+		w.robot = next
+		push.Moved, push.Boxes = true, boxes
 	}
+
+	w.history = append(w.history, push)
+	return push.Moved
 }
 
-func FindBox(warehouse [][]rune, side Point) (Point, Point) {
-	switch warehouse[side.r][side.c] {
-	case '[':
-		left := side
-		right := Point{
-			side.r,
-			side.c + 1,
+// Run makes every move, sending the warehouse to sink after each one
+func (w *Warehouse) Run(moves []Point, sink render.Sink) error {
+	if render.Discarding(sink) {
+		// drawing a frame after every move is most of the work, so skip it
+		// when nobody is watching
+		for _, move := range moves {
+			w.Move(move)
 		}
-		if warehouse[right.r][right.c] != ']' {
-			panic(fmt.Sprintf("expected right side of box; got %s", string(warehouse[side.r][side.c])))
+		return nil
+	}
+
+	for _, move := range moves {
+		w.Move(move)
+		if err := sink.Frame(w.Frame()); err != nil {
+			return err
 		}
-		return left, right
-	case ']':
-		left := Point{
-			side.r,
-			side.c - 1,
+	}
+	return nil
+}
+
+// Undo takes back the last move, or false if there's nothing to take back
+func (w *Warehouse) Undo() bool {
+	if len(w.history) == 0 {
+		return false
+	}
+
+	push := w.history[len(w.history)-1]
+	w.history = w.history[:len(w.history)-1]
+	if push.Moved {
+		w.shift(push.Boxes, Point{}.Sub(push.Move))
+		w.robot = push.From
+	}
+	return true
+}
+
+func (w *Warehouse) History() []Push {
+	return slices.Clone(w.history)
+}
+
+// Replay rewinds to the start and makes every move again, sending the
+// warehouse to sink before the first move and after each one
+func (w *Warehouse) Replay(sink render.Sink) error {
+	moves := make([]Point, len(w.history))
+	for i, push := range w.history {
+		moves[i] = push.Move
+	}
+
+	for w.Undo() {
+	}
+
+	if err := sink.Frame(w.Frame()); err != nil {
+		return err
+	}
+	return w.Run(moves, sink)
+}
+
+// GPS is the sum of every box's GPS coordinate
+func (w *Warehouse) GPS() int {
+	var sum int
+	for _, box := range w.boxes {
+		sum += box.GPS()
+	}
+	return sum
+}
+
+func (w *Warehouse) Frame() render.Runes {
+	frame := render.Blank(w.columns, w.rows, '.')
+	for i, wall := range w.walls {
+		if wall {
+			frame[i/w.columns][i%w.columns] = '#'
 		}
-		right := side
-		if warehouse[left.r][left.c] != '[' {
-			panic(fmt.Sprintf("expected left side of box; got %s", string(warehouse[side.r][side.c])))
+	}
+	for _, box := range w.boxes {
+		for cell, glyph := range box.Cells() {
+			frame[cell.r][cell.c] = glyph
 		}
-		return left, right
-	default:
-		panic(fmt.Sprintf("attempting vertical move against %s; not edge of a box", string(warehouse[side.r][side.c])))
-	}
-}
-
-func TryMoveBoxVertically(warehouse [][]rune, start Point, move Point) bool {
-	if move.c != 0 {
-		panic("attempting vertical move with horizontal delta")
-	}
-
-	next := Point{
-		start.r + move.r,
-		start.c,
-	}
-
-	left, right := FindBox(warehouse, next)
-
-	// NB: Using a map[Point]struct{} rather than []Point because we can have fan-in
-	// [][]
-	//  []
-	// where we don't want to overcount (i.e. count same box twice), or fan-out
-	//  []
-	// [][]
-	// where we don't want to undercount (only count one of the two boxes)
-	// Using a set means it's easier to iterate across a frontier and account for every box exactly once
-
-	rows := []map[Point]struct{}{}
-	rows = append(rows, map[Point]struct{}{
-		left:  {},
-		right: {},
-	})
-	// Only care that the top edge of the boxes can move
-	// e.g.
-	// []
-	// [][]
-	//  []
-	// Only need to check the `[]` on the top row, and the right of `[][]` for collisions
-	frontier := map[int]int{
-		left.c:  left.r,
-		right.c: right.r,
-	}
-
-	for {
-		row := map[Point]struct{}{}
-		nextFrontier := map[int]int{}
-		for c, r := range frontier {
-			next := Point{
-				r + move.r,
-				c,
-			}
-			tile := warehouse[next.r][next.c]
-			if tile == '#' {
-				return false
-			} else if tile == '.' {
-				// OK, but need to check rest of frontier
-			} else if tile == '[' || tile == ']' {
-				previous := warehouse[r][c]
-				if tile == previous {
-					// []
-					// []
-					// Box is inline
-
-					nextFrontier[next.c] = next.r
-					row[next] = struct{}{}
-				} else {
-					// Box is staggered with another box
-					// []
-					//  []
-					left, right := FindBox(warehouse, next)
-					nextFrontier[left.c] = left.r
-					nextFrontier[right.c] = right.r
-					row[left] = struct{}{}
-					row[right] = struct{}{}
-				}
-			} else {
-				panic(fmt.Sprintf("unexpected tile %s", string(tile)))
-			}
+	}
+	frame[w.robot.r][w.robot.c] = '@'
+	return frame
+}
+
+func (w *Warehouse) String() string {
+	return PrintWarehouse(w.Frame())
+}
+
+func Part1(input string) (int, error) {
+	return Simulate(input, false, render.Discard)
+}
+
+var palette = render.Palette{
+	Colours: map[rune]color.RGBA{
+		'#': {128, 128, 128, 255},
+		'O': {205, 133, 63, 255},
+		'[': {205, 133, 63, 255},
+		']': {205, 133, 63, 255},
+		'@': {255, 0, 0, 255},
+	},
+	Default: color.RGBA{32, 32, 32, 255},
+}
+
+// Simulate runs the robot's moves around the warehouse, or the expanded
+// warehouse if wide, sending the warehouse to sink after every move, and
+// returns the sum of the boxes' GPS coordinates
+func Simulate(input string, wide bool, sink render.Sink) (int, error) {
+	grid, movements, err := ParseInput(input)
+	if err != nil {
+		return 0, err
+	}
+
+	if wide {
+		if grid, err = ExpandWarehouse(grid); err != nil {
+			return 0, err
 		}
-		if len(nextFrontier) == 0 {
-			for _, row := range slices.Backward(rows) {
-				for point := range row {
-					warehouse[point.r+move.r][point.c] = warehouse[point.r][point.c]
-					warehouse[point.r][point.c] = '.'
-				}
-			}
+	}
 
-			warehouse[start.r][start.c] = '.'
-			if next.c == left.c {
-				warehouse[left.r][left.c] = '@'
-				warehouse[right.r][right.c] = '.'
-			} else {
-				warehouse[left.r][left.c] = '.'
-				warehouse[right.r][right.c] = '@'
+	warehouse, err := NewWarehouse(grid)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := warehouse.Run(movements, sink); err != nil {
+		return 0, err
+	}
+
+	return warehouse.GPS(), nil
+}
+
+// ExpandWarehouse doubles the width of everything, except the robot
+func ExpandWarehouse(warehouse [][]rune) ([][]rune, error) {
+	expanded := make([][]rune, len(warehouse))
+
+	for r, row := range warehouse {
+		expanded[r] = make([]rune, len(row)*2)
+		for c, tile := range row {
+			switch {
+			case tile == '#' || tile == '.':
+				expanded[r][2*c] = tile
+				expanded[r][2*c+1] = tile
+			case tile == 'O':
+				expanded[r][2*c] = '['
+				expanded[r][2*c+1] = ']'
+			case tile == '@':
+				expanded[r][2*c] = '@'
+				expanded[r][2*c+1] = '.'
+			case unicode.IsLetter(tile):
+				expanded[r][2*c] = tile
+				expanded[r][2*c+1] = tile
+			default:
+				return nil, fmt.Errorf("invalid tile %s at row %d column %d", string(tile), r, c)
 			}
-			return true
 		}
-
-		frontier = nextFrontier
-		rows = append(rows, row)
 	}
+
+	return expanded, nil
 }
 
 func PrintWarehouse(warehouse [][]rune) string {
@@ -376,90 +557,6 @@ func PrintMove(move Point) string {
 	}
 }
 
-func CountWarehouse(warehouse [][]rune) (walls int, boxes int, empty int) {
-	for r, row := range warehouse {
-		for c, tile := range row {
-			if tile == '#' {
-				walls += 1
-			} else if tile == '[' && c < len(row) && warehouse[r][c+1] == ']' {
-				boxes += 1
-			} else if tile == '.' {
-				empty += 1
-			}
-		}
-	}
-
-	return walls, boxes, empty
-}
-
 func Part2(input string) (int, error) {
-	warehouse, movements, err := ParseInput(input)
-	if err != nil {
-		return 0, err
-	}
-
-	warehouse = ExpandWarehouse(warehouse)
-
-	sw, sb, se := CountWarehouse(warehouse)
-
-	// fmt.Println(PrintWarehouse(warehouse))
-
-	current, found := FindRobot(warehouse)
-	if !found {
-		return 0, fmt.Errorf("could not find robot @ in warehouse %v", warehouse)
-	}
-
-	for i, move := range movements {
-		// fmt.Println(PrintMove(move))
-
-		next := Point{
-			current.r + move.r,
-			current.c + move.c,
-		}
-
-		switch warehouse[next.r][next.c] {
-		case '@':
-			return 0, fmt.Errorf("invalid move at index %d, robot already at %v", next, i)
-		case '#':
-			// blocked
-		case '.':
-			warehouse[next.r][next.c] = '@'
-			warehouse[current.r][current.c] = '.'
-			current = next
-		case '[':
-			fallthrough
-		case ']':
-			if move.r == 0 {
-				if ok := TryMoveBoxHorizontally(warehouse, current, move); ok {
-					current = next
-				}
-			} else {
-				if ok := TryMoveBoxVertically(warehouse, current, move); ok {
-					current = next
-				}
-			}
-		default:
-			return 0, fmt.Errorf("unexpected location %s at %v", string(warehouse[next.r][next.c]), next)
-		}
-
-		// fmt.Println(PrintWarehouse(warehouse))
-
-		if w, b, e := CountWarehouse(warehouse); w != sw || b != sb || e != se {
-			panic(fmt.Sprintf("change in contents of warehouse from walls=%d, boxes=%d, empty=%d to walls=%d, boxes=%d, empty=%d", sw, sb, se, w, b, e))
-		}
-	}
-
-	var sum int
-
-	for r, row := range warehouse {
-		for c, coordinate := range row {
-			if coordinate != '[' {
-				continue
-			}
-
-			sum += 100*r + c
-		}
-	}
-
-	return sum, nil
+	return Simulate(input, true, render.Discard)
 }
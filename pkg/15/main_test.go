@@ -1,29 +1,21 @@
 package main
 
 import (
+	"errors"
+	"os"
 	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/max-nicholson/advent-of-code-2024/lib/render"
+	"github.com/max-nicholson/advent-of-code-2024/lib/testutil"
 )
 
-func TestPart1(t *testing.T) {
-	result, err := Part1(`########
-#..O.O.#
-##@.O..#
-#...O..#
-#.#.O..#
-#...O..#
-#......#
-########
-
-<^^>>>vv<v>>v<<`)
-	if err != nil {
-		t.Fatal(err)
-	}
-	if result != 2028 {
-		t.Fatalf("expected 2028, got %d", result)
-	}
+func TestExamples(t *testing.T) {
+	testutil.Golden(t, map[string]testutil.Part{
+		"part1": testutil.Content(Part1),
+		"part2": testutil.Content(Part2),
+	})
 }
 
 func setupWarehouse(warehouse string) [][]rune {
@@ -38,7 +30,7 @@ func setupWarehouse(warehouse string) [][]rune {
 	return acc
 }
 
-func TestTryMoveBoxVertically(t *testing.T) {
+func TestMoveWideBoxesVertically(t *testing.T) {
 	cases := []struct {
 		name      string
 		move      Point
@@ -117,23 +109,23 @@ func TestTryMoveBoxVertically(t *testing.T) {
 
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
-			warehouse := setupWarehouse(c.warehouse)
-
-			start, ok := FindRobot(warehouse)
-			if !ok {
-				t.Errorf("TryMoveBoxVertically() no robot found in warehouse\n%s", PrintWarehouse(warehouse))
+			warehouse, err := NewWarehouse(setupWarehouse(c.warehouse))
+			if err != nil {
+				t.Fatal(err)
 			}
 
-			TryMoveBoxVertically(warehouse, start, c.move)
+			if !warehouse.Move(c.move) {
+				t.Errorf("Move() blocked")
+			}
 
-			if !cmp.Equal(warehouse, setupWarehouse(c.want)) {
-				t.Errorf("TryMoveBoxVertically() want \n%s \ngot \n%s", c.want, PrintWarehouse(warehouse))
+			if !cmp.Equal(warehouse.Frame(), render.Runes(setupWarehouse(c.want))) {
+				t.Errorf("Move() want \n%s \ngot \n%s", c.want, warehouse)
 			}
 		})
 	}
 }
 
-func TestTryMoveBoxHorizontally(t *testing.T) {
+func TestMoveWideBoxesHorizontally(t *testing.T) {
 	cases := []struct {
 		name      string
 		move      Point
@@ -168,77 +160,216 @@ func TestTryMoveBoxHorizontally(t *testing.T) {
 
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
-			warehouse := setupWarehouse(c.warehouse)
-
-			start, ok := FindRobot(warehouse)
-			if !ok {
-				t.Errorf("TryMoveBoxHorizontally() no robot found in warehouse\n%s", PrintWarehouse(warehouse))
+			warehouse, err := NewWarehouse(setupWarehouse(c.warehouse))
+			if err != nil {
+				t.Fatal(err)
 			}
 
-			TryMoveBoxHorizontally(warehouse, start, c.move)
+			if !warehouse.Move(c.move) {
+				t.Errorf("Move() blocked")
+			}
 
-			if !cmp.Equal(warehouse, setupWarehouse(c.want)) {
-				t.Errorf("TryMoveBoxHorizontally() want %s got %s", c.want, PrintWarehouse(warehouse))
+			if !cmp.Equal(warehouse.Frame(), render.Runes(setupWarehouse(c.want))) {
+				t.Errorf("Move() want %s got %s", c.want, warehouse)
 			}
 		})
 	}
 }
 
-func TestPart2(t *testing.T) {
-	cases := []struct {
-		name  string
-		input string
-		want  int
+func TestMoveIsAllOrNothing(t *testing.T) {
+	grid := `########
+#..#...#
+#.[][].#
+#..[]..#
+#...@..#
+########`
+	warehouse, err := NewWarehouse(setupWarehouse(grid))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if warehouse.Move(Point{r: -1}) {
+		t.Errorf("Move() pushed boxes into a wall")
+	}
+	if got := warehouse.String(); got != grid+"\n" {
+		t.Errorf("Move() want \n%s\ngot \n%s", grid, got)
+	}
+}
+
+func TestRaggedWarehouse(t *testing.T) {
+	// the robot's row stops short, so it and the box are up against the
+	// edge of the input
+	warehouse, err := NewWarehouse(setupWarehouse(`######
+#..@O
+#....#
+######`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if warehouse.Move(Point{c: 1}) {
+		t.Errorf("Move() pushed a box past the end of a short row")
+	}
+	if !warehouse.Move(Point{r: 1}) || !warehouse.Move(Point{c: 1}) {
+		t.Fatalf("Move() couldn't get under the box\n%s", warehouse)
+	}
+	if warehouse.Move(Point{r: -1}) {
+		t.Errorf("Move() pushed a box up into a wall")
+	}
+
+	want := `######
+#...O#
+#...@#
+######
+`
+	if got := warehouse.String(); got != want {
+		t.Errorf("Move() want \n%s\ngot \n%s", want, got)
+	}
+}
+
+// muted throws frames away, and fails if it's sent any
+type muted struct{}
+
+func (muted) Frame(render.Frame) error { return errors.New("drew a frame for a discarding sink") }
+func (muted) Discarding() bool         { return true }
+
+func TestRunSkipsDiscardedFrames(t *testing.T) {
+	warehouse, err := NewWarehouse(setupWarehouse(`#####
+#@O.#
+#####`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := warehouse.Run([]Point{{c: 1}, {c: 1}}, muted{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := warehouse.Robot(); got != (Point{1, 2}) {
+		t.Errorf("Robot() = %v, want {1 2}", got)
+	}
+}
+
+type frames struct {
+	render.Sink
+	got []string
+}
+
+func (f *frames) Frame(frame render.Frame) error {
+	f.got = append(f.got, PrintWarehouse(frame.(render.Runes)))
+	return nil
+}
+
+func TestUndoAndReplay(t *testing.T) {
+	input, err := os.ReadFile("testdata/larger.in")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	grid, moves, err := ParseInput(strings.TrimSpace(string(input)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if grid, err = ExpandWarehouse(grid); err != nil {
+		t.Fatal(err)
+	}
+
+	warehouse, err := NewWarehouse(grid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := warehouse.String()
+
+	run := &frames{}
+	if err := warehouse.Run(moves, run); err != nil {
+		t.Fatal(err)
+	}
+	if got := warehouse.GPS(); got != 9021 {
+		t.Errorf("GPS() = %d, want 9021", got)
+	}
+
+	replay := &frames{}
+	if err := warehouse.Replay(replay); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(append([]string{start}, run.got...), replay.got); diff != "" {
+		t.Errorf("Replay() mismatch (-want +got):\n%s", diff)
+	}
+
+	for i := len(moves) - 1; i >= 0; i-- {
+		if !warehouse.Undo() {
+			t.Fatalf("Undo() nothing to undo at move %d", i)
+		}
+		want := start
+		if i > 0 {
+			want = run.got[i-1]
+		}
+		if got := warehouse.String(); got != want {
+			t.Fatalf("Undo() move %d want \n%s\ngot \n%s", i, want, got)
+		}
+	}
+	if warehouse.Undo() {
+		t.Errorf("Undo() past the first move")
+	}
+}
+
+func TestPolyominoBoxes(t *testing.T) {
+	warehouse, err := NewWarehouse(setupWarehouse(`#########
+#.......#
+#..AA...#
+#..A.B..#
+#..@.B..#
+#....BB.#
+#########`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	boxes := warehouse.Boxes()
+	if len(boxes) != 2 || len(boxes[0].Shape.Offsets) != 3 || len(boxes[1].Shape.Offsets) != 4 {
+		t.Fatalf("Boxes() = %v", boxes)
+	}
+	// A's closest edges are row 2 and column 3, B's are row 3 and column 5
+	if got := warehouse.GPS(); got != 203+305 {
+		t.Errorf("GPS() = %d, want %d", got, 203+305)
+	}
+
+	for i, move := range []struct {
+		move  Point
+		moved bool
 	}{
-		{
-			name: "Small example",
-			input: `#######
-#...#.#
-#.....#
-#..OO@#
-#..O..#
-#.....#
-#######
-
-<vv<<^^<<^^`,
-			want: 618,
-		},
-		{
-			name: "larger example",
-			input: `##########
-#..O..O.O#
-#......O.#
-#.OO..O.O#
-#..O@..O.#
-#O#..O...#
-#O..O..O.#
-#.OO.O.OO#
-#....O...#
-##########
-
-<vv>^<v^>v>^vv^v>v<>v^v<v<^vv<<<^><<><>>v<vvv<>^v^>^<<<><<v<<<v^vv^v>^
-vvv<<^>^v^^><<>>><>^<<><^vv^^<>vvv<>><^^v>^>vv<>v<<<<v<^v>^<^^>>>^<v<v
-><>vv>v^v^<>><>>>><^^>vv>v<^^^>>v^v^<^^>v^^>v^<^v>v<>>v^v^<v>v^^<^^vv<
-<<v<^>>^^^^>>>v^<>vvv^><v<<<>^^^vv^<vvv>^>v<^^^^v<>^>vvvv><>>v^<<^^^^^
-^><^><>>><>^^<<^^v>>><^<v>^<vv>>v>>>^v><>^v><<<<v>>v<v<v>vvv>^<><<>^><
-^>><>^v<><^vvv<^^<><v<<<<<><^v<<<><<<^^<v<^^^><^>>^<v^><<<^>>^v<v^v<v^
->^>>^v>vv>^<<^v<>><<><<v<<v><>v<^vv<<<>^^v^>^^>>><<^v>>v^v><^^>>^<>vv^
-<><^^>^^^<><vvvvv^v<v<<>^v<v>v<<^><<><<><<<^^<<<^<<>><<><^^^>^^<>^>v<>
-^^>vv<^v^v<vv>^<><v<^v>^^^>>>^^vvv^>vvv<>>>^<^>>>>>^<<^v>^vvv<>^<><<v>
-v^^>>><<^^<>>^v^<v^vv<>v^<<>^<^v^v><^<<<><<^<v><v<>vv>>v><v^<vv<>v^<<^
-`,
-			want: 9021,
-		},
+		{Point{r: -1}, true},
+		{Point{c: 1}, true},
+		{Point{c: 1}, true},
+		{Point{c: 1}, false},
+	} {
+		if got := warehouse.Move(move.move); got != move.moved {
+			t.Fatalf("Move() %d = %t, want %t\n%s", i, got, move.moved, warehouse)
+		}
 	}
-	for _, c := range cases {
-		t.Run(c.name, func(t *testing.T) {
-			got, err := Part2(c.input)
-			if err != nil {
-				t.Error(err)
-			}
-			if got != c.want {
-				t.Errorf("Part2() want %d got %d", c.want, got)
-			}
-		})
+
+	want := `#########
+#..AA...#
+#..A....#
+#....@B.#
+#.....B.#
+#.....BB#
+#########
+`
+	if got := warehouse.String(); got != want {
+		t.Errorf("Move() want \n%s\ngot \n%s", want, got)
+	}
+	if got := warehouse.GPS(); got != 103+306 {
+		t.Errorf("GPS() = %d, want %d", got, 103+306)
+	}
+}
+
+func TestInvalidWarehouse(t *testing.T) {
+	for _, grid := range []string{"#@]#", "#@[.#", "#@?#", "#..#"} {
+		if _, err := NewWarehouse(setupWarehouse(grid)); err == nil {
+			t.Errorf("NewWarehouse(%q) want error", grid)
+		}
+	}
+	if _, err := ExpandWarehouse(setupWarehouse("#@?#")); err == nil {
+		t.Errorf("ExpandWarehouse() want error")
 	}
 }
@@ -2,9 +2,14 @@ package main
 
 import (
 	"container/heap"
+	"errors"
 	"fmt"
+	"iter"
 	"log"
 	"math"
+	"math/big"
+	"slices"
+	"strings"
 
 	"github.com/max-nicholson/advent-of-code-2024/lib"
 )
@@ -18,46 +23,6 @@ func (a Point) Equal(b Point) bool {
 	return a.row == b.row && a.column == b.column
 }
 
-// An Item is something we manage in a priority queue.
-type Item struct {
-	value    Node // The value of the item; arbitrary.
-	priority int  // The priority of the item in the queue.
-	// The index is needed by update and is maintained by the heap.Interface methods.
-	index int // The index of the item in the heap.
-}
-
-// A PriorityQueue implements heap.Interface and holds Items.
-type PriorityQueue []*Item
-
-func (pq PriorityQueue) Len() int { return len(pq) }
-
-func (pq PriorityQueue) Less(i, j int) bool {
-	return pq[i].priority < pq[j].priority
-}
-
-func (pq PriorityQueue) Swap(i, j int) {
-	pq[i], pq[j] = pq[j], pq[i]
-	pq[i].index = i
-	pq[j].index = j
-}
-
-func (pq *PriorityQueue) Push(x any) {
-	n := len(*pq)
-	item := x.(*Item)
-	item.index = n
-	*pq = append(*pq, item)
-}
-
-func (pq *PriorityQueue) Pop() any {
-	old := *pq
-	n := len(old)
-	item := old[n-1]
-	old[n-1] = nil  // don't stop the GC from reclaiming the item eventually
-	item.index = -1 // for safety
-	*pq = old[0 : n-1]
-	return item
-}
-
 func main() {
 	lines, err := lib.ReadLines("pkg/16/input.txt")
 	if err != nil {
@@ -103,201 +68,457 @@ func (g Grid) Find(target rune) (Point, error) {
 	return Point{}, fmt.Errorf("cell not found")
 }
 
-type Direction Point
+// Heading is the way the reindeer is facing, in clockwise order
+type Heading int
 
-func (a Direction) Equal(b Direction) bool {
-	return a.row == b.row && a.column == b.column
-}
+const (
+	East Heading = iota
+	South
+	West
+	North
+)
 
-func (d Direction) Reverse() Direction {
-	return Direction{row: -d.row, column: -d.column}
-}
+var headings = [4]Heading{East, South, West, North}
 
-var directions = []Direction{
-	{0, 1},
-	{0, -1},
-	{1, 0},
-	{-1, 0},
+var deltas = [4]Point{
+	East:  {0, 1},
+	South: {1, 0},
+	West:  {0, -1},
+	North: {-1, 0},
 }
 
-type Node struct {
-	point     Point
-	direction Direction
+func (h Heading) String() string {
+	return string(">v<^"[h])
 }
 
-type Edge struct {
-	node   Node
-	weight int
+// Turns is how many quarter turns it takes to face to instead
+func (h Heading) Turns(to Heading) int {
+	turns := (int(to) - int(h) + 4) % 4
+	return min(turns, 4-turns)
 }
 
-func (point Point) Move(direction Direction) Point {
+func (point Point) Move(heading Heading) Point {
 	return Point{
-		row:    point.row + direction.row,
-		column: point.column + direction.column,
+		row:    point.row + deltas[heading].row,
+		column: point.column + deltas[heading].column,
 	}
 }
 
-func (point Point) MinCost(costs map[Node]int) int {
-	min := math.MaxInt32
-	for _, direction := range directions {
-		min = lib.Min(min, costs[Node{point: point, direction: direction}])
-	}
-	return min
+// Costs are the points it takes to step forward one tile, and to turn a
+// quarter turn on the spot
+type Costs struct {
+	Move int
+	Turn int
 }
 
-func (g Grid) Edges(from Node) []Edge {
-	edges := make([]Edge, 0)
+var DefaultCosts = Costs{Move: 1, Turn: 1000}
 
-	for _, direction := range []Direction{
-		{0, 1},
-		{0, -1},
-		{1, 0},
-		{-1, 0},
-	} {
-		to := from.point.Move(direction)
+var ErrNoPath = errors.New("no path from start to end")
 
-		if g[to.row][to.column] == '#' {
-			continue
-		}
+// Maze is the grid as a graph of (tile, heading) nodes, where each edge turns
+// to face a neighbouring tile then steps onto it
+type Maze struct {
+	grid    Grid
+	columns int
+	start   Point
+	end     Point
+	costs   Costs
+	heading Heading
+}
 
-		node := Node{
-			point:     to,
-			direction: direction,
-		}
+func NewMaze(lines []string, costs Costs, heading Heading) (*Maze, error) {
+	if costs.Move < 1 || costs.Turn < 0 {
+		return nil, fmt.Errorf("invalid costs %+v; moves must cost at least 1 and turns can't be negative", costs)
+	}
 
-		if direction.Equal(from.direction) {
-			edges = append(edges, Edge{
-				node:   node,
-				weight: 1,
-			})
-		} else if direction.Equal(from.direction.Reverse()) {
-			edges = append(edges, Edge{
-				node:   node,
-				weight: 2001,
-			})
-		} else {
-			edges = append(edges, Edge{
-				node:   node,
-				weight: 1001,
-			})
-		}
+	grid := parseGrid(lines)
+
+	start, err := grid.Find('S')
+	if err != nil {
+		return nil, fmt.Errorf("start not found")
 	}
 
-	return edges
+	end, err := grid.Find('E')
+	if err != nil {
+		return nil, fmt.Errorf("end not found")
+	}
+
+	columns := 0
+	for _, row := range grid {
+		columns = max(columns, len(row))
+	}
+
+	return &Maze{
+		grid:    grid,
+		columns: columns,
+		start:   start,
+		end:     end,
+		costs:   costs,
+		heading: heading,
+	}, nil
+}
+
+func (m *Maze) open(p Point) bool {
+	return p.row >= 0 && p.row < len(m.grid) && p.column >= 0 && p.column < len(m.grid[p.row]) && m.grid[p.row][p.column] != '#'
+}
+
+// node is the index of the reindeer being at point facing heading
+func (m *Maze) node(point Point, heading Heading) int {
+	return (point.row*m.columns+point.column)*4 + int(heading)
+}
+
+func (m *Maze) point(node int) Point {
+	return Point{node / 4 / m.columns, node / 4 % m.columns}
 }
 
-func dijkstra(grid Grid, start Point) map[Node]int {
-	costs := make(map[Node]int, len(grid)*len(grid[0]))
-	pq := make(PriorityQueue, 0)
-	heap.Init(&pq)
+func (m *Maze) size() int {
+	return len(m.grid) * m.columns * 4
+}
 
-	for i, row := range grid {
-		for j := range row {
-			for _, direction := range directions {
-				costs[Node{point: Point{row: i, column: j}, direction: direction}] = math.MaxInt32
+// edges is every node one move on from node, and what it costs to get there
+func (m *Maze) edges(node int) iter.Seq2[int, int] {
+	return func(yield func(int, int) bool) {
+		from, facing := m.point(node), Heading(node%4)
+		for _, heading := range headings {
+			to := from.Move(heading)
+			if !m.open(to) {
+				continue
+			}
+
+			if !yield(m.node(to, heading), m.costs.Move+m.costs.Turn*facing.Turns(heading)) {
+				return
 			}
 		}
 	}
+}
 
-	costs[Node{point: start, direction: Direction{0, 1}}] = 0
-	heap.Push(&pq, &Item{value: Node{point: start, direction: Direction{0, 1}}})
+// Path is the moves the reindeer makes from the start
+type Path struct {
+	Start   Point
+	Heading Heading
+	Moves   []Heading
+	Cost    int
+}
 
-	for pq.Len() > 0 {
-		u := heap.Pop(&pq).(*Item).value
+// String draws the moves as arrows, e.g. >>^^>
+func (p Path) String() string {
+	var b strings.Builder
+	for _, move := range p.Moves {
+		b.WriteString(move.String())
+	}
+	return b.String()
+}
 
-		for _, edge := range grid.Edges(u) {
-			v := edge.node
-			weight := edge.weight
+// Tiles is every tile the path visits, in order
+func (p Path) Tiles() []Point {
+	tiles := []Point{p.Start}
+	for _, move := range p.Moves {
+		tiles = append(tiles, tiles[len(tiles)-1].Move(move))
+	}
+	return tiles
+}
 
-			if costs[u]+weight < costs[v] {
-				costs[v] = costs[u] + weight
-				heap.Push(&pq, &Item{value: v, priority: costs[v]})
+func (m *Maze) path(nodes []int, cost int) Path {
+	path := Path{Start: m.start, Heading: m.heading, Cost: cost, Moves: make([]Heading, len(nodes)-1)}
+	for i, node := range nodes[1:] {
+		path.Moves[i] = Heading(node % 4)
+	}
+	return path
+}
+
+// Solution is every cheapest way through the maze
+type Solution struct {
+	maze  *Maze
+	costs []int
+	ways  []*big.Int
+	from  [][]int
+	ends  []int
+	Cost  int
+}
+
+// Solve runs Dijkstra from the start, keeping every predecessor on a cheapest
+// path to each node along with how many cheapest paths there are to it
+func (m *Maze) Solve() (*Solution, error) {
+	s := &Solution{
+		maze:  m,
+		costs: make([]int, m.size()),
+		ways:  make([]*big.Int, m.size()),
+		from:  make([][]int, m.size()),
+	}
+	for i := range s.costs {
+		s.costs[i] = math.MaxInt
+	}
+
+	start := m.node(m.start, m.heading)
+	s.costs[start] = 0
+	s.ways[start] = big.NewInt(1)
+
+	done := make([]bool, m.size())
+	pq := make(lib.PriorityQueue[int], 0)
+	heap.Push(&pq, &lib.PriorityQueueItem[int]{Value: start})
+
+	for pq.Len() > 0 {
+		u := heap.Pop(&pq).(*lib.PriorityQueueItem[int]).Value
+		if done[u] {
+			continue
+		}
+		done[u] = true
+
+		for v, weight := range m.edges(u) {
+			cost := s.costs[u] + weight
+			switch {
+			case cost < s.costs[v]:
+				s.costs[v] = cost
+				s.ways[v] = new(big.Int).Set(s.ways[u])
+				s.from[v] = append(s.from[v][:0], u)
+				heap.Push(&pq, &lib.PriorityQueueItem[int]{Value: v, Priority: cost})
+			case cost == s.costs[v]:
+				s.ways[v].Add(s.ways[v], s.ways[u])
+				s.from[v] = append(s.from[v], u)
 			}
+		}
+	}
 
+	s.Cost = math.MaxInt
+	for _, heading := range headings {
+		s.Cost = min(s.Cost, s.costs[m.node(m.end, heading)])
+	}
+	if s.Cost == math.MaxInt {
+		return nil, ErrNoPath
+	}
+
+	for _, heading := range headings {
+		if node := m.node(m.end, heading); s.costs[node] == s.Cost {
+			s.ends = append(s.ends, node)
 		}
 	}
 
-	return costs
+	return s, nil
 }
 
-func Part1(lines []string) (int, error) {
-	grid := parseGrid(lines)
+// Count is how many cheapest paths there are, without listing them
+func (s *Solution) Count() *big.Int {
+	count := new(big.Int)
+	for _, end := range s.ends {
+		count.Add(count, s.ways[end])
+	}
+	return count
+}
 
-	start, err := grid.Find('S')
-	if err != nil {
-		return 0, fmt.Errorf("start not found")
+// Tiles is how many tiles are on at least one of the cheapest paths
+func (s *Solution) Tiles() int {
+	visited := map[int]struct{}{}
+	queue := slices.Clone(s.ends)
+	for _, end := range s.ends {
+		visited[end] = struct{}{}
 	}
 
-	end, err := grid.Find('E')
-	if err != nil {
-		return 0, fmt.Errorf("end not found")
+	for len(queue) > 0 {
+		current := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+
+		for _, previous := range s.from[current] {
+			if _, seen := visited[previous]; !seen {
+				visited[previous] = struct{}{}
+				queue = append(queue, previous)
+			}
+		}
 	}
 
-	costs := dijkstra(grid, start)
+	points := map[Point]struct{}{}
+	for node := range visited {
+		points[s.maze.point(node)] = struct{}{}
+	}
 
-	return end.MinCost(costs), nil
+	return len(points)
 }
 
-func Part2(lines []string) (int, error) {
-	grid := parseGrid(lines)
+// Paths lists every cheapest path. There can be a lot of them, see Count
+func (s *Solution) Paths() iter.Seq[Path] {
+	return func(yield func(Path) bool) {
+		start := s.maze.node(s.maze.start, s.maze.heading)
+		reversed := []int{}
+
+		var walk func(node int) bool
+		walk = func(node int) bool {
+			reversed = append(reversed, node)
+			defer func() { reversed = reversed[:len(reversed)-1] }()
+
+			if node == start {
+				nodes := slices.Clone(reversed)
+				slices.Reverse(nodes)
+				return yield(s.maze.path(nodes, s.Cost))
+			}
 
-	start, err := grid.Find('S')
-	if err != nil {
-		return 0, fmt.Errorf("start not found")
-	}
+			for _, previous := range s.from[node] {
+				if !walk(previous) {
+					return false
+				}
+			}
+			return true
+		}
 
-	end, err := grid.Find('E')
-	if err != nil {
-		return 0, fmt.Errorf("end not found")
+		for _, end := range s.ends {
+			if !walk(end) {
+				return
+			}
+		}
 	}
+}
 
-	costs := dijkstra(grid, start)
+// route is a path as the nodes it goes through, with the cost of getting to
+// each of them
+type route struct {
+	nodes []int
+	costs []int
+}
 
-	min := end.MinCost(costs)
+// shortest is the cheapest route from one node to the end that doesn't use any
+// banned edges or step on any banned tiles
+func (m *Maze) shortest(from int, edges map[[2]int]bool, tiles map[Point]bool) (route, bool) {
+	costs := map[int]int{from: 0}
+	previous := map[int]int{}
+	done := map[int]bool{}
 
-	visited := map[Node]struct{}{}
-	queue := []Node{}
+	pq := make(lib.PriorityQueue[int], 0)
+	heap.Push(&pq, &lib.PriorityQueueItem[int]{Value: from})
 
-	for _, direction := range directions {
-		node := Node{point: end, direction: direction}
-		if costs[node] == min {
-			queue = append(queue, node)
-			visited[node] = struct{}{}
+	for pq.Len() > 0 {
+		u := heap.Pop(&pq).(*lib.PriorityQueueItem[int]).Value
+		if done[u] {
+			continue
+		}
+		done[u] = true
+
+		if m.point(u) == m.end {
+			r := route{}
+			for node := u; ; node = previous[node] {
+				r.nodes = append(r.nodes, node)
+				r.costs = append(r.costs, costs[node])
+				if node == from {
+					break
+				}
+			}
+			slices.Reverse(r.nodes)
+			slices.Reverse(r.costs)
+			return r, true
 		}
-	}
 
-	for len(queue) > 0 {
-		current := queue[len(queue)-1]
-		queue = queue[:len(queue)-1]
+		for v, weight := range m.edges(u) {
+			if edges[[2]int{u, v}] || tiles[m.point(v)] {
+				continue
+			}
 
-		for _, prevDirection := range directions {
-			previous := Node{
-				point:     current.point.Move(current.direction.Reverse()),
-				direction: prevDirection,
+			if cost, seen := costs[v]; !seen || costs[u]+weight < cost {
+				costs[v] = costs[u] + weight
+				previous[v] = u
+				heap.Push(&pq, &lib.PriorityQueueItem[int]{Value: v, Priority: costs[v]})
 			}
+		}
+	}
 
-			var cost int
-			if current.direction.Equal(prevDirection) {
-				cost = 1
-			} else if current.direction.Equal(prevDirection.Reverse()) {
-				cost = 2001
-			} else {
-				cost = 1001
+	return route{}, false
+}
+
+// Shortest is the k cheapest paths through the maze that never visit the same
+// tile twice, cheapest first, using Yen's algorithm
+func (m *Maze) Shortest(k int) []Path {
+	first, ok := m.shortest(m.node(m.start, m.heading), nil, map[Point]bool{m.start: true})
+	if !ok || k < 1 {
+		return nil
+	}
+
+	found := []route{first}
+	candidates := make(lib.PriorityQueue[route], 0)
+	seen := func(nodes []int) bool {
+		for _, r := range found {
+			if slices.Equal(r.nodes, nodes) {
+				return true
+			}
+		}
+		for _, c := range candidates {
+			if slices.Equal(c.Value.nodes, nodes) {
+				return true
 			}
+		}
+		return false
+	}
 
-			if costs[previous]+cost == costs[current] {
-				if _, seen := visited[previous]; !seen {
-					visited[previous] = struct{}{}
-					queue = append(queue, previous)
+	for len(found) < k {
+		last := found[len(found)-1]
+		for i := range len(last.nodes) - 1 {
+			root := last.nodes[:i+1]
+			spur := last.nodes[i]
+
+			// don't take the same next step as any path with this root
+			edges := map[[2]int]bool{}
+			for _, r := range found {
+				if len(r.nodes) > i+1 && slices.Equal(r.nodes[:i+1], root) {
+					edges[[2]int{r.nodes[i], r.nodes[i+1]}] = true
 				}
 			}
+
+			// and don't go back over the root
+			tiles := map[Point]bool{}
+			for _, node := range root {
+				tiles[m.point(node)] = true
+			}
+
+			rest, ok := m.shortest(spur, edges, tiles)
+			if !ok {
+				continue
+			}
+
+			candidate := route{
+				nodes: append(slices.Clone(root), rest.nodes[1:]...),
+				costs: slices.Clone(last.costs[:i+1]),
+			}
+			for _, cost := range rest.costs[1:] {
+				candidate.costs = append(candidate.costs, last.costs[i]+cost)
+			}
+
+			if !seen(candidate.nodes) {
+				heap.Push(&candidates, &lib.PriorityQueueItem[route]{Value: candidate, Priority: candidate.costs[len(candidate.costs)-1]})
+			}
+		}
+
+		if candidates.Len() == 0 {
+			break
 		}
+		found = append(found, heap.Pop(&candidates).(*lib.PriorityQueueItem[route]).Value)
 	}
 
-	points := map[Point]struct{}{}
-	for node := range visited {
-		points[node.point] = struct{}{}
+	paths := make([]Path, len(found))
+	for i, r := range found {
+		paths[i] = m.path(r.nodes, r.costs[len(r.costs)-1])
+	}
+	return paths
+}
+
+func Part1(lines []string) (int, error) {
+	maze, err := NewMaze(lines, DefaultCosts, East)
+	if err != nil {
+		return 0, err
+	}
+
+	solution, err := maze.Solve()
+	if err != nil {
+		return 0, err
+	}
+
+	return solution.Cost, nil
+}
+
+func Part2(lines []string) (int, error) {
+	maze, err := NewMaze(lines, DefaultCosts, East)
+	if err != nil {
+		return 0, err
+	}
+
+	solution, err := maze.Solve()
+	if err != nil {
+		return 0, err
 	}
 
-	return len(points), nil
+	return solution.Tiles(), nil
 }
@@ -1,115 +1,200 @@
 package main
 
 import (
-	"strconv"
-	"strings"
+	"math/big"
+	"slices"
+	"sort"
 	"testing"
+
+	"github.com/max-nicholson/advent-of-code-2024/lib/testutil"
 )
 
-func TestPart1(t *testing.T) {
-	for i, tc := range []struct {
-		input string
-		want  int
-	}{
-		{input: `###############
-#.......#....E#
-#.#.###.#.###.#
-#.....#.#...#.#
-#.###.#####.#.#
-#.#.#.......#.#
-#.#.#####.###.#
-#...........#.#
-###.#.#####.#.#
-#...#.....#.#.#
-#.#.#.###.#.#.#
-#.....#...#.#.#
-#.###.#.#.#.#.#
-#S..#.....#...#
-###############`,
-			want: 7036,
-		},
-		{input: `#################
-#...#...#...#..E#
-#.#.#.#.#.#.#.#.#
-#.#.#.#...#...#.#
-#.#.#.#.###.#.#.#
-#...#.#.#.....#.#
-#.#.#.#.#.#####.#
-#.#...#.#.#.....#
-#.#.#####.#.###.#
-#.#.#.......#...#
-#.#.###.#####.###
-#.#.#...#.....#.#
-#.#.#.#####.###.#
-#.#.#.........#.#
-#.#.#.#########.#
-#S#.............#
-#################`,
-			want: 11048,
-		},
-	} {
-		t.Run(strconv.Itoa(i), func(t *testing.T) {
-			got, err := Part1(strings.Split(tc.input, "\n"))
+func TestExamples(t *testing.T) {
+	testutil.Golden(t, map[string]testutil.Part{
+		"part1": testutil.Lines(Part1),
+		"part2": testutil.Lines(Part2),
+	})
+}
+
+var example = []string{
+	"###############",
+	"#.......#....E#",
+	"#.#.###.#.###.#",
+	"#.....#.#...#.#",
+	"#.###.#####.#.#",
+	"#.#.#.......#.#",
+	"#.#.#####.###.#",
+	"#...........#.#",
+	"###.#.#####.#.#",
+	"#...#.....#.#.#",
+	"#.#.#.###.#.#.#",
+	"#.....#...#.#.#",
+	"#.###.#.#.#.#.#",
+	"#S..#.....#...#",
+	"###############",
+}
+
+// walk follows the path's moves, checking it stays out of walls, and adds up
+// what it costs
+func walk(t *testing.T, maze *Maze, path Path) int {
+	t.Helper()
+
+	cost, heading := 0, path.Heading
+	for i, tile := range path.Tiles()[1:] {
+		if !maze.open(tile) {
+			t.Fatalf("path %s walks into a wall at %v", path, tile)
+		}
+		cost += maze.costs.Move + maze.costs.Turn*heading.Turns(path.Moves[i])
+		heading = path.Moves[i]
+	}
+
+	if end := path.Tiles()[len(path.Moves)]; end != maze.end {
+		t.Fatalf("path %s ends at %v, not the end", path, end)
+	}
+	return cost
+}
+
+func TestPaths(t *testing.T) {
+	for _, heading := range headings {
+		t.Run(heading.String(), func(t *testing.T) {
+			maze, err := NewMaze(example, DefaultCosts, heading)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			solution, err := maze.Solve()
 			if err != nil {
 				t.Fatal(err)
 			}
-			if got != tc.want {
-				t.Fatalf("expected %d, got %d", tc.want, got)
+
+			tiles := map[Point]struct{}{}
+			var count int64
+			for path := range solution.Paths() {
+				count++
+				if got := walk(t, maze, path); got != solution.Cost || path.Cost != solution.Cost {
+					t.Errorf("path %s costs %d, labelled %d, want %d", path, got, path.Cost, solution.Cost)
+				}
+				for _, tile := range path.Tiles() {
+					tiles[tile] = struct{}{}
+				}
+			}
+
+			if got := solution.Count(); got.Cmp(big.NewInt(count)) != 0 {
+				t.Errorf("Count() = %s, want %d", got, count)
+			}
+			if got := solution.Tiles(); got != len(tiles) {
+				t.Errorf("Tiles() = %d, want %d", got, len(tiles))
 			}
 		})
 	}
 }
 
-func TestPart2(t *testing.T) {
-	for i, tc := range []struct {
-		input string
-		want  int
-	}{
-		{input: `###############
-#.......#....E#
-#.#.###.#.###.#
-#.....#.#...#.#
-#.###.#####.#.#
-#.#.#.......#.#
-#.#.#####.###.#
-#...........#.#
-###.#.#####.#.#
-#...#.....#.#.#
-#.#.#.###.#.#.#
-#.....#...#.#.#
-#.###.#.#.#.#.#
-#S..#.....#...#
-###############`,
-			want: 45,
-		},
-		{input: `#################
-#...#...#...#..E#
-#.#.#.#.#.#.#.#.#
-#.#.#.#...#...#.#
-#.#.#.#.###.#.#.#
-#...#.#.#.....#.#
-#.#.#.#.#.#####.#
-#.#...#.#.#.....#
-#.#.#####.#.###.#
-#.#.#.......#...#
-#.#.###.#####.###
-#.#.#...#.....#.#
-#.#.#.#####.###.#
-#.#.#.........#.#
-#.#.#.#########.#
-#S#.............#
-#################`,
-			want: 64,
-		},
-	} {
-		t.Run(strconv.Itoa(i), func(t *testing.T) {
-			got, err := Part2(strings.Split(tc.input, "\n"))
-			if err != nil {
-				t.Fatal(err)
+func TestCountWithoutTurnCosts(t *testing.T) {
+	lines := []string{
+		"#######",
+		"#S....#",
+		"#.....#",
+		"#.....#",
+		"#.....#",
+		"#....E#",
+		"#######",
+	}
+	maze, err := NewMaze(lines, Costs{Move: 1, Turn: 0}, East)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	solution, err := maze.Solve()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// any 4 of the 8 moves can be the ones going down
+	if solution.Cost != 8 || solution.Count().Cmp(big.NewInt(70)) != 0 {
+		t.Errorf("Solve() = cost %d count %s, want cost 8 count 70", solution.Cost, solution.Count())
+	}
+}
+
+func TestNoPath(t *testing.T) {
+	maze, err := NewMaze([]string{"#####", "#S#E#", "#####"}, DefaultCosts, East)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := maze.Solve(); err != ErrNoPath {
+		t.Errorf("Solve() = %v, want ErrNoPath", err)
+	}
+	if paths := maze.Shortest(3); len(paths) != 0 {
+		t.Errorf("Shortest() = %v, want none", paths)
+	}
+}
+
+// simplePaths is the cost of every path from start to end that never visits a
+// tile twice
+func simplePaths(maze *Maze) []int {
+	var costs []int
+	visited := map[Point]bool{maze.start: true}
+
+	var dfs func(at Point, heading Heading, cost int)
+	dfs = func(at Point, heading Heading, cost int) {
+		if at == maze.end {
+			costs = append(costs, cost)
+			return
+		}
+		for _, next := range headings {
+			to := at.Move(next)
+			if !maze.open(to) || visited[to] {
+				continue
 			}
-			if got != tc.want {
-				t.Fatalf("expected %d, got %d", tc.want, got)
+			visited[to] = true
+			dfs(to, next, cost+maze.costs.Move+maze.costs.Turn*heading.Turns(next))
+			visited[to] = false
+		}
+	}
+	dfs(maze.start, maze.heading, 0)
+
+	sort.Ints(costs)
+	return costs
+}
+
+func TestShortestMatchesBruteForce(t *testing.T) {
+	lines := []string{
+		"#######",
+		"#S....#",
+		"#.#.#.#",
+		"#.....#",
+		"#.#.#.#",
+		"#....E#",
+		"#######",
+	}
+
+	for _, costs := range []Costs{DefaultCosts, {Move: 1, Turn: 0}, {Move: 3, Turn: 2}} {
+		maze, err := NewMaze(lines, costs, North)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := simplePaths(maze)
+		paths := maze.Shortest(len(want) + 5)
+		if len(paths) != len(want) {
+			t.Fatalf("Shortest() found %d paths, want %d", len(paths), len(want))
+		}
+
+		got := make([]int, len(paths))
+		seen := map[string]bool{}
+		for i, path := range paths {
+			got[i] = walk(t, maze, path)
+			if got[i] != path.Cost {
+				t.Errorf("path %s costs %d, labelled %d", path, got[i], path.Cost)
 			}
-		})
+			if seen[path.String()] {
+				t.Errorf("path %s found twice", path)
+			}
+			seen[path.String()] = true
+		}
+
+		if !slices.Equal(got, want) {
+			t.Errorf("Shortest() costs %v, want %v", got, want)
+		}
 	}
 }
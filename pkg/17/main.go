@@ -1,22 +1,45 @@
 package main
 
 import (
+	"bufio"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"iter"
 	"log"
+	"math/bits"
+	"os"
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/max-nicholson/advent-of-code-2024/lib"
 )
 
+var repl = flag.Bool("repl", false, "Debug the program interactively instead of solving it")
+
 func main() {
+	flag.Parse()
+
 	lines, err := lib.ReadLines("pkg/17/input.txt")
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	if *repl {
+		program, err := ParseProgram(lines)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if err := REPL(NewDebugger(program), os.Stdin, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	part1, err := Part1(lines)
 	if err != nil {
 		log.Fatal(err)
@@ -47,6 +70,25 @@ const (
 	cdv
 )
 
+var mnemonics = [8]string{"adv", "bxl", "bst", "jnz", "bxc", "out", "bdv", "cdv"}
+
+func (o Opcode) String() string {
+	if o < 0 || int(o) >= len(mnemonics) {
+		return fmt.Sprintf("op%d", int(o))
+	}
+	return mnemonics[o]
+}
+
+// combo is whether the opcode's operand is a combo operand, rather than a
+// literal
+func (o Opcode) combo() bool {
+	switch o {
+	case adv, bst, out, bdv, cdv:
+		return true
+	}
+	return false
+}
+
 func ParseOpcode(raw string) (Opcode, error) {
 	opcode, err := strconv.ParseInt(raw, 10, 4)
 	if err != nil {
@@ -81,11 +123,63 @@ func (o Operand) Combo(registers Registers) int {
 	panic(fmt.Sprintf("invalid operand %d", o))
 }
 
+// ComboName is how the operand reads as a combo operand: a literal 0-3 or the
+// register it refers to
+func (o Operand) ComboName() string {
+	switch o {
+	case 4:
+		return "A"
+	case 5:
+		return "B"
+	case 6:
+		return "C"
+	}
+	return strconv.Itoa(int(o))
+}
+
 type Instruction struct {
 	opcode  Opcode
 	operand Operand
 }
 
+// String disassembles the instruction, e.g. adv 3, bxl 5 or out B
+func (i Instruction) String() string {
+	switch {
+	case i.opcode == bxc:
+		return i.opcode.String()
+	case i.opcode.combo():
+		return i.opcode.String() + " " + i.operand.ComboName()
+	default:
+		return fmt.Sprintf("%s %d", i.opcode, i.operand)
+	}
+}
+
+// execute runs a single instruction against the registers, returning what it
+// outputs, if anything, and where it jumps to, if anywhere
+func (i Instruction) execute(registers *Registers) (output int, outputs bool, jump int, jumps bool) {
+	switch i.opcode {
+	case adv:
+		registers.A.Value = int(registers.A.Value / lib.PowInt(2, i.operand.Combo(*registers)))
+	case bxl:
+		registers.B.Value ^= int(i.operand)
+	case bst:
+		registers.B.Value = i.operand.Combo(*registers) % 8
+	case jnz:
+		if registers.A.Value != 0 {
+			return 0, false, int(i.operand), true
+		}
+	case bxc:
+		registers.B.Value ^= registers.C.Value
+	case out:
+		return i.operand.Combo(*registers) % 8, true, 0, false
+	case bdv:
+		registers.B.Value = int(registers.A.Value / lib.PowInt(2, i.operand.Combo(*registers)))
+	case cdv:
+		registers.C.Value = int(registers.A.Value / lib.PowInt(2, i.operand.Combo(*registers)))
+	}
+	return 0, false, 0, false
+}
+
 type Registers struct {
 	A Register
 	B Register
@@ -169,42 +263,476 @@ func (p Program) Output() iter.Seq[int] {
 		for instructionPointer < len(p.instructions)*2 {
 			instruction := p.instructions[instructionPointer/2]
 
-			switch instruction.opcode {
-			case adv:
-				registers.A.Value = int(registers.A.Value / lib.PowInt(2, instruction.operand.Combo(registers)))
-			case bxl:
-				registers.B.Value ^= int(instruction.operand)
-			case bst:
-				registers.B.Value = instruction.operand.Combo(registers) % 8
-			case bxc:
-				registers.B.Value ^= registers.C.Value
-			case out:
-				s := instruction.operand.Combo(registers) % 8
-				if !yield(s) {
-					return
-				}
-			case bdv:
-				registers.B.Value = int(registers.A.Value / lib.PowInt(2, instruction.operand.Combo(registers)))
-			case cdv:
-				registers.C.Value = int(registers.A.Value / lib.PowInt(2, instruction.operand.Combo(registers)))
+			output, outputs, jump, jumps := instruction.execute(&registers)
+			if outputs && !yield(output) {
+				return
 			}
 
-			if instruction.opcode == jnz && registers.A.Value != 0 {
-				instructionPointer = int(instruction.operand)
+			if jumps {
+				instructionPointer = jump
 			} else {
 				instructionPointer += 2
 			}
-
 		}
 	}
 }
 
 func (p Program) InstructionsByOpcode(opcode Opcode) iter.Seq[Instruction] {
 	return lib.Filter(slices.Values(p.instructions), func(i Instruction) bool {
-		return i.opcode == adv
+		return i.opcode == opcode
 	})
 }
 
+// Disassemble lists the program one instruction per line, along with the
+// instruction pointer it's at
+func (p Program) Disassemble() string {
+	var b strings.Builder
+	for i, instruction := range p.instructions {
+		fmt.Fprintf(&b, "%2d: %s\n", i*2, instruction)
+	}
+	return b.String()
+}
+
+func (r Registers) String() string {
+	return fmt.Sprintf("A=%d B=%d C=%d", r.A.Value, r.B.Value, r.C.Value)
+}
+
+// Snapshot is the machine just after running one instruction
+type Snapshot struct {
+	Step        int
+	Pointer     int
+	Instruction Instruction
+	Registers   Registers
+	Output      int
+	Outputs     bool
+}
+
+func (s Snapshot) String() string {
+	line := fmt.Sprintf("%6d %2d: %-6s %s", s.Step, s.Pointer, s.Instruction, s.Registers)
+	if s.Outputs {
+		line += fmt.Sprintf(" out=%d", s.Output)
+	}
+	return line
+}
+
+var (
+	ErrHalted    = errors.New("program has halted")
+	ErrStepLimit = errors.New("step limit reached")
+)
+
+// Stop is why the debugger stopped running the program
+type Stop int
+
+const (
+	Halted Stop = iota
+	Breakpoint
+	StepLimit
+)
+
+func (s Stop) String() string {
+	return [...]string{"halted", "breakpoint", "step limit"}[s]
+}
+
+// Debugger runs a program one instruction at a time. Limit stops programs that
+// never halt, after that many steps in total (0 for no limit)
+type Debugger struct {
+	program     Program
+	Registers   Registers
+	Pointer     int
+	Steps       int
+	Output      []int
+	Breakpoints map[int]bool
+	Limit       int
+}
+
+func NewDebugger(program Program) *Debugger {
+	return &Debugger{
+		program:     program,
+		Registers:   program.registers,
+		Breakpoints: map[int]bool{},
+	}
+}
+
+// Reset puts the debugger back to the start of the program, keeping its
+// breakpoints and limit
+func (d *Debugger) Reset() {
+	d.Registers = d.program.registers
+	d.Pointer = 0
+	d.Steps = 0
+	d.Output = nil
+}
+
+// Load swaps in a new program, and resets to its start
+func (d *Debugger) Load(program Program) {
+	d.program = program
+	d.Reset()
+}
+
+func (d *Debugger) Halted() bool {
+	return d.Pointer < 0 || d.Pointer >= len(d.program.instructions)*2
+}
+
+// Step runs the next instruction
+func (d *Debugger) Step() (Snapshot, error) {
+	if d.Halted() {
+		return Snapshot{}, ErrHalted
+	}
+	if d.Limit > 0 && d.Steps >= d.Limit {
+		return Snapshot{}, ErrStepLimit
+	}
+	if d.Pointer%2 != 0 {
+		return Snapshot{}, fmt.Errorf("instruction pointer %d is odd; jumping into an operand isn't supported", d.Pointer)
+	}
+
+	instruction := d.program.instructions[d.Pointer/2]
+	if instruction.opcode.combo() && instruction.operand == 7 {
+		return Snapshot{}, fmt.Errorf("invalid combo operand 7 at instruction pointer %d", d.Pointer)
+	}
+
+	snapshot := Snapshot{Step: d.Steps, Pointer: d.Pointer, Instruction: instruction}
+	output, outputs, jump, jumps := instruction.execute(&d.Registers)
+	if outputs {
+		d.Output = append(d.Output, output)
+		snapshot.Output, snapshot.Outputs = output, true
+	}
+	if jumps {
+		d.Pointer = jump
+	} else {
+		d.Pointer += 2
+	}
+	d.Steps++
+
+	snapshot.Registers = d.Registers
+	return snapshot, nil
+}
+
+// Continue runs until the program halts, hits the step limit, or is about to
+// run an instruction with a breakpoint on it, sending each step to trace
+func (d *Debugger) Continue(trace func(Snapshot)) (Stop, error) {
+	for {
+		snapshot, err := d.Step()
+		switch {
+		case errors.Is(err, ErrHalted):
+			return Halted, nil
+		case errors.Is(err, ErrStepLimit):
+			return StepLimit, nil
+		case err != nil:
+			return 0, err
+		}
+
+		if trace != nil {
+			trace(snapshot)
+		}
+
+		if d.Breakpoints[d.Pointer] && !d.Halted() {
+			return Breakpoint, nil
+		}
+	}
+}
+
+// Trace runs the program from the start, yielding the machine after every
+// instruction, and ErrStepLimit if it's still going after limit steps (0 for no
+// limit)
+func (p Program) Trace(limit int) iter.Seq2[Snapshot, error] {
+	return func(yield func(Snapshot, error) bool) {
+		d := NewDebugger(p)
+		d.Limit = limit
+		for {
+			snapshot, err := d.Step()
+			if errors.Is(err, ErrHalted) {
+				return
+			}
+			if !yield(snapshot, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+const replHelp = `commands:
+  s, step [n]          run the next n instructions (default 1)
+  c, continue          run until a breakpoint, the step limit or the program halts
+  b, break <ip>        stop before running the instruction at ip
+  d, delete <ip>       remove the breakpoint at ip
+  limit <n>            stop after n steps in total (0 for no limit)
+  r, regs              show the registers
+  set <A|B|C> <value>  change a register
+  l, list              disassemble the program
+  o, output            show the output so far
+  program <p>          load a program, e.g. program 0,3,5,4,3,0
+  reset                go back to the start
+  h, help              show this help
+  q, quit              exit
+`
+
+// REPL reads debugger commands from in until it runs out or is told to quit
+func REPL(d *Debugger, in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	fmt.Fprint(out, "(17) ")
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 0 {
+			if fields[0] == "q" || fields[0] == "quit" {
+				return nil
+			}
+			if err := command(d, fields, out); err != nil {
+				fmt.Fprintf(out, "error: %v\n", err)
+			}
+		}
+		fmt.Fprint(out, "(17) ")
+	}
+	return scanner.Err()
+}
+
+func command(d *Debugger, fields []string, out io.Writer) error {
+	argument := func(i int) (int, error) {
+		if len(fields) <= i {
+			return 0, fmt.Errorf("%s needs %d argument(s)", fields[0], i)
+		}
+		return strconv.Atoi(fields[i])
+	}
+
+	switch fields[0] {
+	case "s", "step":
+		n := 1
+		if len(fields) > 1 {
+			var err error
+			if n, err = argument(1); err != nil {
+				return err
+			}
+		}
+		for range n {
+			snapshot, err := d.Step()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(out, snapshot)
+		}
+	case "c", "continue":
+		stop, err := d.Continue(func(s Snapshot) { fmt.Fprintln(out, s) })
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "stopped: %s at %d\n", stop, d.Pointer)
+	case "b", "break", "d", "delete":
+		ip, err := argument(1)
+		if err != nil {
+			return err
+		}
+		if fields[0] == "b" || fields[0] == "break" {
+			d.Breakpoints[ip] = true
+		} else {
+			delete(d.Breakpoints, ip)
+		}
+	case "limit":
+		limit, err := argument(1)
+		if err != nil {
+			return err
+		}
+		d.Limit = limit
+	case "r", "regs":
+		fmt.Fprintf(out, "%s ip=%d steps=%d\n", d.Registers, d.Pointer, d.Steps)
+	case "set":
+		value, err := argument(2)
+		if err != nil {
+			return err
+		}
+		switch fields[1] {
+		case "A", "a":
+			d.Registers.A.Value = value
+		case "B", "b":
+			d.Registers.B.Value = value
+		case "C", "c":
+			d.Registers.C.Value = value
+		default:
+			return fmt.Errorf("unknown register %s", fields[1])
+		}
+	case "l", "list":
+		for i, instruction := range d.program.instructions {
+			marker := "  "
+			if i*2 == d.Pointer {
+				marker = "=>"
+			} else if d.Breakpoints[i*2] {
+				marker = " *"
+			}
+			fmt.Fprintf(out, "%s %2d: %s\n", marker, i*2, instruction)
+		}
+	case "o", "output":
+		output := make([]string, len(d.Output))
+		for i, v := range d.Output {
+			output[i] = strconv.Itoa(v)
+		}
+		fmt.Fprintln(out, strings.Join(output, ","))
+	case "program":
+		if len(fields) < 2 {
+			return fmt.Errorf("program needs a comma separated program")
+		}
+		instructions, err := ParseInstructions("Program: " + fields[1])
+		if err != nil {
+			return err
+		}
+		d.Load(Program{registers: d.Registers, instructions: instructions})
+	case "reset":
+		d.Reset()
+	case "h", "help":
+		fmt.Fprint(out, replHelp)
+	default:
+		return fmt.Errorf("unknown command %s; try help", fields[0])
+	}
+
+	return nil
+}
+
+// step is one compiled instruction. It updates the registers (A, B, C) and
+// returns the index of the next instruction to run, along with any output
+type step func(registers *[3]int) (next int, output int, outputs bool)
+
+// Compiled is a program turned into a closure per instruction, with operands
+// resolved up front and divisions by powers of 2 done as shifts. Registers are
+// assumed not to go negative, where a shift would round differently
+type Compiled struct {
+	registers [3]int
+	steps     []step
+}
+
+func (p Program) Compile() (Compiled, error) {
+	compiled := Compiled{
+		registers: [3]int{p.registers.A.Value, p.registers.B.Value, p.registers.C.Value},
+		steps:     make([]step, len(p.instructions)),
+	}
+
+	for i, instruction := range p.instructions {
+		next := i + 1
+		operand := int(instruction.operand)
+
+		// combo operands are either a literal, or one of the registers
+		register := -1
+		if instruction.opcode.combo() {
+			switch {
+			case operand < 4:
+			case operand < 7:
+				register = operand - 4
+			default:
+				return Compiled{}, fmt.Errorf("invalid combo operand 7 at instruction pointer %d", i*2)
+			}
+		}
+
+		switch instruction.opcode {
+		case adv, bdv, cdv:
+			destination := map[Opcode]int{adv: 0, bdv: 1, cdv: 2}[instruction.opcode]
+			if register < 0 {
+				compiled.steps[i] = func(registers *[3]int) (int, int, bool) {
+					registers[destination] = registers[0] >> operand
+					return next, 0, false
+				}
+			} else {
+				compiled.steps[i] = func(registers *[3]int) (int, int, bool) {
+					registers[destination] = registers[0] >> uint(registers[register])
+					return next, 0, false
+				}
+			}
+		case bxl:
+			compiled.steps[i] = func(registers *[3]int) (int, int, bool) {
+				registers[1] ^= operand
+				return next, 0, false
+			}
+		case bst:
+			if register < 0 {
+				compiled.steps[i] = func(registers *[3]int) (int, int, bool) {
+					registers[1] = operand
+					return next, 0, false
+				}
+			} else {
+				compiled.steps[i] = func(registers *[3]int) (int, int, bool) {
+					registers[1] = registers[register] & 7
+					return next, 0, false
+				}
+			}
+		case jnz:
+			// an odd target lands on an operand, which the interpreter would
+			// read as an opcode, but there's no closure to jump to
+			if operand%2 != 0 {
+				return Compiled{}, fmt.Errorf("odd jump target %d at instruction pointer %d", operand, i*2)
+			}
+			target := operand / 2
+			compiled.steps[i] = func(registers *[3]int) (int, int, bool) {
+				if registers[0] != 0 {
+					return target, 0, false
+				}
+				return next, 0, false
+			}
+		case bxc:
+			compiled.steps[i] = func(registers *[3]int) (int, int, bool) {
+				registers[1] ^= registers[2]
+				return next, 0, false
+			}
+		case out:
+			if register < 0 {
+				compiled.steps[i] = func(registers *[3]int) (int, int, bool) {
+					return next, operand, true
+				}
+			} else {
+				compiled.steps[i] = func(registers *[3]int) (int, int, bool) {
+					return next, registers[register] & 7, true
+				}
+			}
+		default:
+			return Compiled{}, fmt.Errorf("invalid opcode %d at instruction pointer %d", instruction.opcode, i*2)
+		}
+	}
+
+	return compiled, nil
+}
+
+// Output runs the compiled program with register A set to a
+func (c Compiled) Output(a int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		registers := c.registers
+		registers[0] = a
+		for i := 0; i < len(c.steps); {
+			next, output, outputs := c.steps[i](&registers)
+			if outputs && !yield(output) {
+				return
+			}
+			i = next
+		}
+	}
+}
+
+// Matches is whether the program outputs exactly target with register A set
+// to a, stopping as soon as it doesn't
+func (c Compiled) Matches(a int, target []int) bool {
+	i := 0
+	for output := range c.Output(a) {
+		if i >= len(target) || output != target[i] {
+			return false
+		}
+		i++
+	}
+	return i == len(target)
+}
+
+// Batch runs the program for every value of register A, split across workers,
+// returning the outputs in the same order
+func (c Compiled) Batch(as []int, workers int) [][]int {
+	outputs := make([][]int, len(as))
+	workers = max(1, min(workers, len(as)))
+
+	var wg sync.WaitGroup
+	for worker := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := worker; i < len(as); i += workers {
+				outputs[i] = slices.Collect(c.Output(as[i]))
+			}
+		}()
+	}
+	wg.Wait()
+
+	return outputs
+}
+
 func Part1(lines []string) (string, error) {
 	program, err := ParseProgram(lines)
 	if err != nil {
@@ -225,57 +753,396 @@ func Part2(lines []string) (int, error) {
 		return 0, err
 	}
 
-	o := strings.Split(lines[4][9:], ",")
-	output := make([]int, len(o))
-	for i, v := range o {
-		output[i], err = strconv.Atoi(v)
-		if err != nil {
-			return 0, err
+	return program.Quine()
+}
+
+var ErrNoSolution = errors.New("no value of A outputs the target")
+
+// width is how many bits of A the solver considers, keeping A a positive int
+const width = 63
+
+// maxSteps is how many instructions the solver runs down any one path before
+// giving up on it as a loop which never halts
+const maxSteps = 1 << 12
+
+// bit is one bit of a register, as the XOR of some of A's starting bits (mask)
+// and a constant. Every instruction only shifts, truncates and XORs registers,
+// so this is enough to describe any bit the program ever sees
+type bit struct {
+	mask uint64
+	one  bool
+}
+
+func (b bit) xor(o bit) bit {
+	return bit{b.mask ^ o.mask, b.one != o.one}
+}
+
+type word [width]bit
+
+func constant(value int) word {
+	var w word
+	for i := range w {
+		w[i].one = value>>i&1 == 1
+	}
+	return w
+}
+
+func variable() word {
+	var w word
+	for i := range w {
+		w[i].mask = 1 << i
+	}
+	return w
+}
+
+func (w word) shift(amount int) word {
+	var shifted word
+	if amount < width {
+		copy(shifted[:], w[amount:])
+	}
+	return shifted
+}
+
+// low keeps the lowest n bits
+func (w word) low(n int) word {
+	var truncated word
+	copy(truncated[:n], w[:n])
+	return truncated
+}
+
+// system is what's known about A's starting bits, as linear equations over
+// GF(2) kept in reduced row echelon form, each row pivoting on its lowest bit
+type system struct {
+	rows   [width]bit
+	pivots uint64
+}
+
+// reduce rewrites b in terms of the bits that are still free
+func (s *system) reduce(b bit) bit {
+	for pivots := b.mask & s.pivots; pivots != 0; pivots = b.mask & s.pivots {
+		b = b.xor(s.rows[bits.TrailingZeros64(pivots)])
+	}
+	return b
+}
+
+// assume adds the equation b = value, or false if that contradicts what's
+// already known
+func (s *system) assume(b bit, value bool) bool {
+	b = s.reduce(b)
+	if b.mask == 0 {
+		return b.one == value
+	}
+
+	b.one = b.one != value
+	pivot := bits.TrailingZeros64(b.mask)
+	for rest := s.pivots; rest != 0; rest &= rest - 1 {
+		if row := bits.TrailingZeros64(rest); s.rows[row].mask&(1<<pivot) != 0 {
+			s.rows[row] = s.rows[row].xor(b)
 		}
 	}
+	s.rows[pivot] = b
+	s.pivots |= 1 << pivot
+	return true
+}
+
+// minimum is the lowest A satisfying the system. Each pivot only depends on
+// higher free bits, so setting every free bit to 0 from the top down is best
+func (s *system) minimum() int {
+	var a int
+	for rest := s.pivots; rest != 0; rest &= rest - 1 {
+		if pivot := bits.TrailingZeros64(rest); s.rows[pivot].one {
+			a |= 1 << pivot
+		}
+	}
+	return a
+}
+
+// state is the machine part way through running with a symbolic A
+type state struct {
+	registers [3]word
+	pointer   int
+	outputs   int
+	steps     int
+	system    system
+
+	// jumped is the last jnz taken, and whether anything has changed since, to
+	// spot loops that go round without changing anything and so never halt
+	jumped  int
+	pivots  uint64
+	changed bool
+}
+
+func (st *state) set(register int, value word) {
+	if st.registers[register] != value {
+		st.registers[register] = value
+		st.changed = true
+	}
+}
 
-	// Expect program to have a certain shape, otherwise it's probably not solveable?
-	// Or at least, the solution is much more complicated
-	if lastInstruction := program.instructions[len(program.instructions)-1]; lastInstruction.opcode != jnz || lastInstruction.operand != 0 {
-		return 0, fmt.Errorf("can only handle programs which loop entirely")
+// jump takes the jnz at the current pointer, or false if the machine has come
+// back round to it exactly as it was last time
+func (st *state) jump(to int) bool {
+	if st.jumped == st.pointer && !st.changed && st.pivots == st.system.pivots {
+		return false
 	}
 
-	advInstructions := slices.Collect(program.InstructionsByOpcode(adv))
-	if len(advInstructions) != 1 {
-		return 0, fmt.Errorf("can only handle programs with a single `adv` instruction")
+	st.jumped, st.pivots, st.changed = st.pointer, st.system.pivots, false
+	st.pointer = to
+	return true
+}
+
+func (st *state) combo(operand Operand) (word, bool) {
+	switch operand {
+	case 0, 1, 2, 3:
+		return constant(int(operand)), true
+	case 4, 5, 6:
+		return st.registers[operand-4], true
+	}
+	return word{}, false
+}
+
+// solver searches depth first, keeping the paths still to explore on a stack
+// rather than recursing, since a program that loops can branch thousands of
+// times down a single path
+type solver struct {
+	instructions []Instruction
+	target       []int
+	stack        []state
+	best         int
+	found        bool
+	abandoned    bool
+}
+
+// MinimumA finds the lowest value of register A for which the program outputs
+// output, for any program. It runs the program with A's bits as unknowns,
+// branching whenever the next step depends on their values (a shift by a
+// register, or a jump), and builds up the equations A's bits must satisfy to
+// produce the output, so whole families of A are ruled out at once
+func (program Program) MinimumA(output []int) (int, error) {
+	if program.registers.B.Value < 0 || program.registers.C.Value < 0 {
+		return 0, fmt.Errorf("registers B and C must not be negative")
 	}
-	if advInstructions[0].operand > 3 {
-		return 0, fmt.Errorf("can only handle programs where `adv` operand is a literal")
+
+	sv := &solver{instructions: program.instructions, target: output}
+	sv.stack = append(sv.stack, state{
+		registers: [3]word{
+			variable(),
+			constant(program.registers.B.Value),
+			constant(program.registers.C.Value),
+		},
+		jumped: -1,
+	})
+	for len(sv.stack) > 0 {
+		st := sv.stack[len(sv.stack)-1]
+		sv.stack = sv.stack[:len(sv.stack)-1]
+		sv.run(st)
 	}
 
-	if len(slices.Collect(program.InstructionsByOpcode(out))) != 1 {
-		return 0, fmt.Errorf("can only handle programs with a single `out` instruction")
+	if !sv.found && sv.abandoned {
+		return 0, fmt.Errorf("%w: %v, without looping more than %d steps", ErrNoSolution, output, maxSteps)
 	}
+	if !sv.found {
+		return 0, fmt.Errorf("%w: %v", ErrNoSolution, output)
+	}
+	return sv.best, nil
+}
 
-	// Only care about the loop body
-	program.instructions = program.instructions[:len(program.instructions)-1]
+// Quine finds the lowest value of register A for which the program outputs a
+// copy of itself
+func (program Program) Quine() (int, error) {
+	return program.MinimumA(program.Code())
+}
 
-	var solve func(instruction int, target int) int
-	solve = func(instruction int, target int) int {
-		if instruction < 0 {
-			return target
+// Code is the program as the 3-bit numbers it was written as
+func (program Program) Code() []int {
+	code := make([]int, 0, len(program.instructions)*2)
+	for _, instruction := range program.instructions {
+		code = append(code, int(instruction.opcode), int(instruction.operand))
+	}
+	return code
+}
+
+// run follows one path until it ends or branches, pushing the branches to
+// explore next, lowest A first
+func (sv *solver) run(st state) {
+	var branches []state
+	push := func(st state) {
+		branches = append(branches, st)
+	}
+	defer func() {
+		for _, branch := range slices.Backward(branches) {
+			sv.stack = append(sv.stack, branch)
+		}
+	}()
+
+	for {
+		if sv.found && st.system.minimum() >= sv.best {
+			return
 		}
 
-		// Only 8 possible values to check with 8-bit integers
-		for t := range 8 {
-			program.registers.A.Value = (target << 3) + t
-			for v := range program.Output() {
-				if v == output[instruction] {
-					sub := solve(instruction-1, program.registers.A.Value)
-					if sub != 0 {
-						return sub
-					}
+		if st.pointer < 0 || st.pointer >= len(sv.instructions)*2 {
+			if st.outputs == len(sv.target) {
+				sv.best, sv.found = st.system.minimum(), true
+			}
+			return
+		}
+
+		if st.steps >= maxSteps {
+			sv.abandoned = true
+			return
+		}
+		st.steps++
+
+		instruction := sv.instructions[st.pointer/2]
+		switch instruction.opcode {
+		case adv, bdv, cdv:
+			destination := map[Opcode]int{adv: 0, bdv: 1, cdv: 2}[instruction.opcode]
+			sv.amount(st, instruction.operand, func(st state, amount int) {
+				st.set(destination, st.registers[0].shift(amount))
+				st.pointer += 2
+				push(st)
+			})
+			return
+		case bxl:
+			for i := range 3 {
+				if instruction.operand>>i&1 == 1 {
+					st.registers[1][i].one = !st.registers[1][i].one
+					st.changed = true
+				}
+			}
+		case bst:
+			value, ok := st.combo(instruction.operand)
+			if !ok {
+				return
+			}
+			st.set(1, value.low(3))
+		case jnz:
+			a := st.registers[0]
+			zero := st
+			if zero.allZero(a[:]) {
+				zero.pointer += 2
+				push(zero)
+			}
+			sv.nonZero(st, a[:], func(st state) {
+				if st.jump(int(instruction.operand)) {
+					push(st)
 				}
+			})
+			return
+		case bxc:
+			b := st.registers[1]
+			for i := range width {
+				b[i] = b[i].xor(st.registers[2][i])
+			}
+			st.set(1, b)
+		case out:
+			value, ok := st.combo(instruction.operand)
+			if !ok || st.outputs >= len(sv.target) {
+				return
 			}
+			for i := range 3 {
+				if !st.system.assume(value[i], sv.target[st.outputs]>>i&1 == 1) {
+					return
+				}
+			}
+			st.outputs++
+			st.changed = true
+		}
+
+		if instruction.opcode != jnz {
+			st.pointer += 2
+		}
+	}
+}
+
+func (st *state) allZero(bs []bit) bool {
+	for _, b := range bs {
+		if !st.system.assume(b, false) {
+			return false
+		}
+	}
+	return true
+}
+
+// nonZero branches on which of bs is the highest set to 1, lowest first
+func (sv *solver) nonZero(st state, bs []bit, k func(state)) {
+	// anything below a bit that's already known to be 1 can't be the highest
+	lowest := 0
+	for i, b := range bs {
+		if b := st.system.reduce(b); b.mask == 0 && b.one {
+			lowest = i
+		}
+	}
+
+	for highest := lowest; highest < len(bs); highest++ {
+		if b := st.system.reduce(bs[highest]); b.mask == 0 && !b.one {
+			continue
+		}
+
+		branch := st
+		if branch.system.assume(bs[highest], true) && branch.allZero(bs[highest+1:]) {
+			k(branch)
+		}
+	}
+}
+
+// amount branches on every value a shift amount can take, capped at width
+// since shifting by any more than that leaves nothing
+func (sv *solver) amount(st state, operand Operand, k func(state, int)) {
+	value, ok := st.combo(operand)
+	if !ok {
+		return
+	}
+
+	// amounts of 64 or more have a bit set above the lowest 6
+	const low = 6
+	small := st
+	if small.allZero(value[low:]) {
+		sv.values(small, value[:low], 0, func(st state, amount int) {
+			k(st, min(amount, width))
+		})
+	}
+	sv.nonZero(st, value[low:], func(st state) {
+		k(st, width)
+	})
+}
+
+// values branches on every value bs can take, fixing the highest bit first
+func (sv *solver) values(st state, bs []bit, value int, k func(state, int)) {
+	if len(bs) == 0 {
+		k(st, value)
+		return
+	}
+
+	i := len(bs) - 1
+	if b := st.system.reduce(bs[i]); b.mask == 0 {
+		if b.one {
+			value |= 1 << i
+		}
+		sv.values(st, bs[:i], value, k)
+		return
+	}
+
+	for _, one := range []bool{false, true} {
+		branch := st
+		branch.system.assume(bs[i], one)
+		if one {
+			sv.values(branch, bs[:i], value|1<<i, k)
+		} else {
+			sv.values(branch, bs[:i], value, k)
 		}
+	}
+}
 
-		return 0
+// MinimumAReference finds the lowest value of register A below limit for which
+// the program outputs target, by running the program for every candidate
+func (program Program) MinimumAReference(target []int, limit int) (int, error) {
+	for a := range limit {
+		program.registers.A.Value = a
+		if slices.Equal(slices.Collect(program.Output()), target) {
+			return a, nil
+		}
 	}
 
-	return solve(len(output)-1, 0), nil
+	return 0, fmt.Errorf("no value of A below %d outputs %v", limit, target)
 }
@@ -1,60 +1,403 @@
 package main
 
 import (
+	"errors"
+	"math/rand/v2"
+	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"testing"
+
+	"github.com/max-nicholson/advent-of-code-2024/lib"
+	"github.com/max-nicholson/advent-of-code-2024/lib/gen"
+	"github.com/max-nicholson/advent-of-code-2024/lib/testutil"
 )
 
-func TestPart1(t *testing.T) {
-	for i, tc := range []struct {
-		input string
-		want  string
-	}{
-		{
-			input: `Register A: 729
-Register B: 0
-Register C: 0
-
-Program: 0,1,5,4,3,0`,
-			want: "4,6,3,5,6,3,5,2,1,0",
-		},
+func TestExamples(t *testing.T) {
+	testutil.Golden(t, map[string]testutil.Part{
+		"part1": testutil.Lines(Part1),
+		"part2": testutil.Lines(Part2),
+	})
+}
+
+func TestMinimumAMatchesReference(t *testing.T) {
+	for seed := range uint64(100) {
+		digits := 1 + int(seed%4)
+		program, err := ParseProgram(strings.Split(gen.Day17(gen.New(seed), digits), "\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		target := slices.Collect(program.Output())
+
+		want, err := program.MinimumAReference(target, lib.PowInt(8, digits))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := program.MinimumA(target)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != want {
+			t.Fatalf("program %v outputting %v: got %d, want %d", program.instructions, target, got, want)
+		}
+	}
+}
+
+func parse(t *testing.T, a int, program string) Program {
+	t.Helper()
+
+	p, err := ParseProgram([]string{
+		"Register A: " + strconv.Itoa(a),
+		"Register B: 0",
+		"Register C: 0",
+		"",
+		"Program: " + program,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestDisassemble(t *testing.T) {
+	want := ` 0: bst A
+ 2: bxl 5
+ 4: cdv B
+ 6: adv 3
+ 8: bxc
+10: out B
+12: jnz 0
+`
+	if got := parse(t, 0, "2,4,1,5,7,5,0,3,4,1,5,5,3,0").Disassemble(); got != want {
+		t.Errorf("Disassemble() = \n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestTrace(t *testing.T) {
+	program := parse(t, 729, "0,1,5,4,3,0")
+
+	var output []int
+	var last Snapshot
+	for snapshot, err := range program.Trace(0) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if snapshot.Outputs {
+			output = append(output, snapshot.Output)
+		}
+		last = snapshot
+	}
+
+	if want := slices.Collect(program.Output()); !slices.Equal(output, want) {
+		t.Errorf("Trace() output %v, want %v", output, want)
+	}
+	// 10 loops of adv, out, jnz
+	if last.Step != 29 || last.Pointer != 4 || last.Registers.A.Value != 0 {
+		t.Errorf("Trace() ended with %v", last)
+	}
+}
+
+func TestTraceStepLimit(t *testing.T) {
+	// jnz 0 forever, since A never changes
+	var steps int
+	var err error
+	for _, err = range parse(t, 1, "1,1,3,0").Trace(100) {
+		if err != nil {
+			break
+		}
+		steps++
+	}
+
+	if steps != 100 || !errors.Is(err, ErrStepLimit) {
+		t.Errorf("Trace() = %d steps, %v; want 100 steps, ErrStepLimit", steps, err)
+	}
+}
+
+func TestDebuggerBreakpoints(t *testing.T) {
+	d := NewDebugger(parse(t, 729, "0,1,5,4,3,0"))
+	d.Breakpoints[2] = true
+
+	for i := range 3 {
+		stop, err := d.Continue(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stop != Breakpoint || d.Pointer != 2 || len(d.Output) != i {
+			t.Fatalf("Continue() = %s at %d with output %v", stop, d.Pointer, d.Output)
+		}
+	}
+
+	delete(d.Breakpoints, 2)
+	if stop, err := d.Continue(nil); err != nil || stop != Halted {
+		t.Fatalf("Continue() = %s, %v; want halted", stop, err)
+	}
+	if _, err := d.Step(); !errors.Is(err, ErrHalted) {
+		t.Errorf("Step() = %v, want ErrHalted", err)
+	}
+}
+
+func TestDebuggerInvalidCombo(t *testing.T) {
+	if _, err := NewDebugger(parse(t, 1, "5,7")).Step(); err == nil {
+		t.Errorf("Step() want error for combo operand 7")
+	}
+}
+
+func TestREPL(t *testing.T) {
+	session := strings.Join([]string{
+		"program 0,1,5,4,3,0",
+		"break 4",
+		"continue",
+		"regs",
+		"set A 3",
+		"step 2",
+		"output",
+		"bogus",
+		"quit",
+		"regs",
+	}, "\n")
+
+	var out strings.Builder
+	if err := REPL(NewDebugger(parse(t, 6, "3,0")), strings.NewReader(session), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `(17) (17) (17)      0  0: adv 1  A=3 B=0 C=0
+     1  2: out A  A=3 B=0 C=0 out=3
+stopped: breakpoint at 4
+(17) A=3 B=0 C=0 ip=4 steps=2
+(17) (17)      2  4: jnz 0  A=3 B=0 C=0
+     3  0: adv 1  A=1 B=0 C=0
+(17) 3
+(17) error: unknown command bogus; try help
+(17) `
+	if got := out.String(); got != want {
+		t.Errorf("REPL() = \n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestInstructionsByOpcode(t *testing.T) {
+	program := parse(t, 0, "2,4,1,5,7,5,0,3,4,1,1,2,5,5,3,0")
+
+	for opcode, want := range map[Opcode][]Instruction{
+		adv: {{adv, 3}},
+		bxl: {{bxl, 5}, {bxl, 2}},
+		bdv: nil,
+		jnz: {{jnz, 0}},
 	} {
-		t.Run(strconv.Itoa(i), func(t *testing.T) {
-			got, err := Part1(strings.Split(tc.input, "\n"))
-			if err != nil {
-				t.Error(err)
+		if got := slices.Collect(program.InstructionsByOpcode(opcode)); !slices.Equal(got, want) {
+			t.Errorf("InstructionsByOpcode(%s) = %v, want %v", opcode, got, want)
+		}
+	}
+}
+
+// run is the program's output from a, or false if it doesn't halt within a
+// few thousand steps or crashes
+func run(program Program, a int) (output []int, ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+
+	program.registers.A.Value = a
+	for snapshot, err := range program.Trace(5000) {
+		if err != nil {
+			return nil, false
+		}
+		if snapshot.Outputs {
+			output = append(output, snapshot.Output)
+		}
+	}
+	return output, true
+}
+
+// randomProgram is any old program: no assumptions about loops, how many
+// times it outputs, or what it shifts A by
+func randomProgram(r *rand.Rand) Program {
+	instructions := make([]Instruction, 2+r.IntN(5))
+	for i := range instructions {
+		opcode := Opcode(r.IntN(8))
+		var operand Operand
+		switch {
+		case opcode == jnz:
+			operand = Operand(2 * r.IntN(len(instructions)))
+		case opcode.combo():
+			operand = Operand(r.IntN(7))
+		default:
+			operand = Operand(r.IntN(8))
+		}
+		instructions[i] = Instruction{opcode, operand}
+	}
+
+	return Program{
+		registers:    Registers{B: Register{r.IntN(4)}, C: Register{r.IntN(4)}},
+		instructions: instructions,
+	}
+}
+
+func TestMinimumAArbitraryPrograms(t *testing.T) {
+	const limit = 1 << 10
+
+	r := rand.New(rand.NewPCG(17, 17))
+	for tested := 0; tested < 200; {
+		program := randomProgram(r)
+		target, ok := run(program, r.IntN(limit))
+		if !ok {
+			continue
+		}
+		tested++
+
+		want := -1
+		for a := range limit {
+			if output, ok := run(program, a); ok && slices.Equal(output, target) {
+				want = a
+				break
+			}
+		}
+
+		got, err := program.MinimumA(target)
+		if err != nil {
+			t.Fatalf("program %v outputting %v: %v", program.instructions, target, err)
+		}
+		if got != want {
+			t.Fatalf("program %v outputting %v: got %d, want %d", program.instructions, target, got, want)
+		}
+	}
+}
+
+func TestQuineNoSolution(t *testing.T) {
+	// outputs once, so can never output both of its own numbers
+	_, err := parse(t, 0, "5,4").Quine()
+	if !errors.Is(err, ErrNoSolution) {
+		t.Errorf("Quine() = %v, want ErrNoSolution", err)
+	}
+}
+
+func TestCompiledMatchesInterpreter(t *testing.T) {
+	for seed := range uint64(20) {
+		program, err := ParseProgram(strings.Split(gen.Day17(gen.New(seed), 8), "\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		compiled, err := program.Compile()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := rand.New(rand.NewPCG(seed, 17))
+		as := make([]int, 100)
+		for i := range as {
+			as[i] = r.IntN(1 << 48)
+		}
+
+		batch := compiled.Batch(as, 4)
+		for i, a := range as {
+			program.registers.A.Value = a
+			want := slices.Collect(program.Output())
+
+			if got := slices.Collect(compiled.Output(a)); !slices.Equal(got, want) {
+				t.Fatalf("program %v with A=%d: Output() = %v, want %v", program.instructions, a, got, want)
 			}
-			if got != tc.want {
-				t.Errorf("got %s, want %s", got, tc.want)
+			if !slices.Equal(batch[i], want) {
+				t.Fatalf("program %v with A=%d: Batch() = %v, want %v", program.instructions, a, batch[i], want)
 			}
-		})
+			if !compiled.Matches(a, want) || compiled.Matches(a, want[1:]) || compiled.Matches(a, append(want, 0)) {
+				t.Fatalf("program %v with A=%d: Matches() wrong for %v", program.instructions, a, want)
+			}
+		}
+	}
+}
+
+func TestCompileInvalidCombo(t *testing.T) {
+	if _, err := parse(t, 0, "0,7").Compile(); err == nil {
+		t.Errorf("Compile() want error for combo operand 7")
 	}
 }
 
-func TestPart2(t *testing.T) {
-	for i, tc := range []struct {
-		input string
-		want  int
-	}{
-		{
-			input: `Register A: 2024
-Register B: 0
-Register C: 0
+func TestCompileOddJump(t *testing.T) {
+	for _, program := range []string{"0,1,3,1", "5,4,3,3", "0,1,5,4,3,7"} {
+		if _, err := parse(t, 1, program).Compile(); err == nil {
+			t.Errorf("Compile(%s) want error for an odd jump target", program)
+		}
+	}
 
-Program: 0,3,5,4,3,0
-`,
-			want: 117440,
-		},
-	} {
-		t.Run(strconv.Itoa(i), func(t *testing.T) {
-			got, err := Part2(strings.Split(tc.input, "\n"))
-			if err != nil {
-				t.Error(err)
+	// even targets past the end of the program halt, as they do when
+	// interpreted
+	program := parse(t, 12, "0,1,5,4,3,6")
+	compiled, err := program.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := slices.Collect(program.Output())
+	if got := slices.Collect(compiled.Output(12)); !slices.Equal(got, want) {
+		t.Errorf("Output() got %v, want %v", got, want)
+	}
+}
+
+func BenchmarkOutput(b *testing.B) {
+	program, err := ParseProgram(strings.Split(gen.Day17(gen.New(1), 16), "\n"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	compiled, err := program.Compile()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	as := make([]int, 1<<12)
+	for i := range as {
+		as[i] = i << 36
+	}
+
+	b.Run("interpreter", func(b *testing.B) {
+		for range b.N {
+			for _, a := range as {
+				program.registers.A.Value = a
+				for range program.Output() {
+				}
 			}
-			if got != tc.want {
-				t.Errorf("got %d, want %d", got, tc.want)
+		}
+	})
+
+	b.Run("compiled", func(b *testing.B) {
+		for range b.N {
+			for _, a := range as {
+				for range compiled.Output(a) {
+				}
 			}
-		})
+		}
+	})
+
+	b.Run("batch", func(b *testing.B) {
+		for range b.N {
+			compiled.Batch(as, runtime.GOMAXPROCS(0))
+		}
+	})
+}
+
+func TestMinimumANonHalting(t *testing.T) {
+	for _, code := range []string{
+		// jnz back to the start forever once A isn't 0, outputting nothing
+		"1,1,3,0",
+		"0,0,3,0",
+		// outputs forever once A isn't 0
+		"5,4,3,0",
+	} {
+		program := parse(t, 0, code)
+		for _, target := range [][]int{{1}, {1, 1, 1}} {
+			_, err := program.MinimumA(target)
+			if !errors.Is(err, ErrNoSolution) {
+				t.Errorf("program %s outputting %v: MinimumA() = %v, want ErrNoSolution", code, target, err)
+			}
+		}
+	}
+
+	// only halts when A is 0, which outputs nothing
+	if a, err := parse(t, 0, "0,0,3,0").MinimumA(nil); err != nil || a != 0 {
+		t.Errorf("MinimumA() = %d, %v, want 0", a, err)
 	}
 }
@@ -1,17 +1,24 @@
 package main
 
 import (
-	"container/heap"
+	"flag"
 	"fmt"
+	"image/color"
 	"log"
 	"math"
+	"slices"
 	"strconv"
 	"strings"
 
 	"github.com/max-nicholson/advent-of-code-2024/lib"
+	"github.com/max-nicholson/advent-of-code-2024/lib/render"
 )
 
+var renderPath = flag.String("render", "", "Render the bytes falling until the exit is cut off to the terminal (-), a .gif or a .png")
+
 func main() {
+	flag.Parse()
+
 	lines, err := lib.ReadLines("pkg/18/input.txt")
 	if err != nil {
 		log.Fatal(err)
@@ -28,6 +35,27 @@ func main() {
 		log.Fatal(err)
 	}
 	fmt.Printf("part2: %v\n", part2)
+
+	if *renderPath != "" {
+		recorder, err := render.Open(*renderPath, palette)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		coordinates, err := ParseCoordinates(lines)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		blocking := slices.Index(coordinates, part2)
+		if err := Render(coordinates[:blocking+1], 70, recorder); err != nil {
+			log.Fatal(err)
+		}
+
+		if err := recorder.Close(); err != nil {
+			log.Fatal(err)
+		}
+	}
 }
 
 type Coordinate struct {
@@ -39,42 +67,8 @@ func (a Coordinate) Equal(b Coordinate) bool {
 	return a.X == b.X && a.Y == b.Y
 }
 
-type Item struct {
-	value    Coordinate
-	priority int
-	index    int
-}
-
-// A PriorityQueue implements heap.Interface and holds Items.
-type PriorityQueue []*Item
-
-func (pq PriorityQueue) Len() int { return len(pq) }
-
-func (pq PriorityQueue) Less(i, j int) bool {
-	return pq[i].priority < pq[j].priority
-}
-
-func (pq PriorityQueue) Swap(i, j int) {
-	pq[i], pq[j] = pq[j], pq[i]
-	pq[i].index = i
-	pq[j].index = j
-}
-
-func (pq *PriorityQueue) Push(x any) {
-	n := len(*pq)
-	item := x.(*Item)
-	item.index = n
-	*pq = append(*pq, item)
-}
-
-func (pq *PriorityQueue) Pop() any {
-	old := *pq
-	n := len(old)
-	item := old[n-1]
-	old[n-1] = nil  // don't stop the GC from reclaiming the item eventually
-	item.index = -1 // for safety
-	*pq = old[0 : n-1]
-	return item
+func (c Coordinate) String() string {
+	return fmt.Sprintf("%d,%d", c.X, c.Y)
 }
 
 func ParseCoordinates(lines []string) ([]Coordinate, error) {
@@ -102,125 +96,216 @@ func ParseCoordinates(lines []string) ([]Coordinate, error) {
 	return coordinates, nil
 }
 
-func Part1(lines []string, bytes int, size int) (int, error) {
-	coordinates, err := ParseCoordinates(lines)
-	if err != nil {
-		return 0, err
-	}
+var palette = render.Palette{
+	Colours: map[rune]color.RGBA{
+		'#': {255, 69, 0, 255},
+	},
+	Default: color.RGBA{16, 16, 48, 255},
+}
+
+// Render draws the memory space as each byte falls into it
+func Render(coordinates []Coordinate, size int, sink render.Sink) error {
+	frame := render.Blank(size+1, size+1, '.')
+
+	for _, coordinate := range coordinates {
+		frame[coordinate.Y][coordinate.X] = '#'
 
-	corrupted := make(map[Coordinate]struct{}, bytes)
-	for i := range bytes {
-		corrupted[coordinates[i]] = struct{}{}
+		if err := sink.Frame(frame); err != nil {
+			return err
+		}
 	}
 
-	end := Coordinate{size, size}
-	pq := make(PriorityQueue, 0)
-	heap.Init(&pq)
-	costs := make(map[Coordinate]int, (size+1)*(size+1))
+	return nil
+}
+
+// Memory is the memory space, from 0,0 to size,size, with the time step each
+// tile is corrupted at. At time step t the first t bytes have fallen
+type Memory struct {
+	size        int
+	coordinates []Coordinate
+	corrupted   []int // time step each tile is corrupted after, or MaxInt
+	blocking    int   // index of the byte which cuts off the exit, or -1
+}
 
-	for y := range size + 1 {
-		for x := range size + 1 {
-			costs[Coordinate{x, y}] = math.MaxInt32
+func NewMemory(coordinates []Coordinate, size int) (*Memory, error) {
+	m := &Memory{
+		size:        size,
+		coordinates: coordinates,
+		corrupted:   make([]int, (size+1)*(size+1)),
+	}
+	for i := range m.corrupted {
+		m.corrupted[i] = math.MaxInt
+	}
+
+	for i, coordinate := range coordinates {
+		if !m.inBounds(coordinate) {
+			return nil, fmt.Errorf("byte %d at %v is outside the memory space", i, coordinate)
 		}
+		m.corrupted[m.index(coordinate)] = min(m.corrupted[m.index(coordinate)], i)
 	}
 
-	start := Coordinate{0, 0}
-	costs[start] = 0
-	heap.Push(&pq, &Item{value: start})
+	m.blocking = m.findBlocking()
+	return m, nil
+}
+
+func (m *Memory) inBounds(c Coordinate) bool {
+	return c.X >= 0 && c.X <= m.size && c.Y >= 0 && c.Y <= m.size
+}
 
-	for pq.Len() > 0 {
-		from := heap.Pop(&pq).(*Item).value
+func (m *Memory) index(c Coordinate) int {
+	return c.Y*(m.size+1) + c.X
+}
 
-		for _, direction := range []Coordinate{
-			{0, 1},
-			{0, -1},
-			{1, 0},
-			{-1, 0},
-		} {
-			to := Coordinate{
-				direction.X + from.X,
-				direction.Y + from.Y,
-			}
+// open is whether the tile is still safe at time step t
+func (m *Memory) open(i int, t int) bool {
+	return m.corrupted[i] >= t
+}
 
-			if _, ok := corrupted[to]; ok {
-				continue
+func (m *Memory) neighbours(i int) []int {
+	x, y := i%(m.size+1), i/(m.size+1)
+	neighbours := make([]int, 0, 4)
+	if x > 0 {
+		neighbours = append(neighbours, i-1)
+	}
+	if x < m.size {
+		neighbours = append(neighbours, i+1)
+	}
+	if y > 0 {
+		neighbours = append(neighbours, i-m.size-1)
+	}
+	if y < m.size {
+		neighbours = append(neighbours, i+m.size+1)
+	}
+	return neighbours
+}
+
+// findBlocking works backwards from every byte having fallen, taking each one
+// away again and joining up the tiles around it, until the start and the exit
+// are connected. The last byte taken away is the first to cut off the exit
+func (m *Memory) findBlocking() int {
+	start, end := 0, len(m.corrupted)-1
+	sets := lib.NewDisjointSet(len(m.corrupted))
+	t := len(m.coordinates)
+
+	join := func(i int) {
+		for _, n := range m.neighbours(i) {
+			if m.open(n, t) {
+				sets.Union(i, n)
 			}
+		}
+	}
+
+	for i := range m.corrupted {
+		if m.open(i, t) {
+			join(i)
+		}
+	}
+
+	connected := func() bool {
+		return m.open(start, t) && m.open(end, t) && sets.Find(start) == sets.Find(end)
+	}
+	if connected() {
+		return -1
+	}
+
+	for t > 0 {
+		t--
+		// only the first byte to land on a tile corrupts it
+		if i := m.index(m.coordinates[t]); m.corrupted[i] == t {
+			join(i)
+		}
+
+		if connected() {
+			return t
+		}
+	}
+
+	// with no bytes fallen every tile is open, so this is never reached
+	return -1
+}
+
+// Reachable is whether there's a path to the exit at time step t
+func (m *Memory) Reachable(t int) bool {
+	return m.blocking < 0 || t <= m.blocking
+}
+
+// Blocking is the first byte that cuts off the exit, and the time step it falls
+// at
+func (m *Memory) Blocking() (Coordinate, int, bool) {
+	if m.blocking < 0 {
+		return Coordinate{}, 0, false
+	}
+	return m.coordinates[m.blocking], m.blocking + 1, true
+}
+
+// ShortestPath is the fewest steps from the start to the exit at time step t,
+// or false if there's no way through
+func (m *Memory) ShortestPath(t int) (int, bool) {
+	start, end := 0, len(m.corrupted)-1
+	if !m.open(start, t) {
+		return 0, false
+	}
+
+	steps := make([]int, len(m.corrupted))
+	for i := range steps {
+		steps[i] = -1
+	}
+	steps[start] = 0
 
-			cost := costs[from] + 1
+	queue := []int{start}
+	for len(queue) > 0 {
+		from := queue[0]
+		queue = queue[1:]
 
-			if cost < costs[to] {
-				costs[to] = cost
-				if !to.Equal(end) {
-					heap.Push(&pq, &Item{value: to, priority: cost})
-				}
+		if from == end {
+			return steps[end], true
+		}
+
+		for _, to := range m.neighbours(from) {
+			if steps[to] < 0 && m.open(to, t) {
+				steps[to] = steps[from] + 1
+				queue = append(queue, to)
 			}
 		}
 	}
 
-	return costs[end], nil
+	return 0, false
 }
 
-func Part2(lines []string, size int) (Coordinate, error) {
+func Part1(lines []string, bytes int, size int) (int, error) {
 	coordinates, err := ParseCoordinates(lines)
 	if err != nil {
-		return Coordinate{}, err
+		return 0, err
 	}
 
-	corrupted := make(map[Coordinate]struct{})
+	memory, err := NewMemory(coordinates, size)
+	if err != nil {
+		return 0, err
+	}
 
-	start := Coordinate{0, 0}
-	end := Coordinate{size, size}
+	steps, ok := memory.ShortestPath(bytes)
+	if !ok {
+		return 0, fmt.Errorf("exit can't be reached after %d bytes", bytes)
+	}
 
-	for _, coordinate := range coordinates {
-		corrupted[coordinate] = struct{}{}
-		visited := map[Coordinate]struct{}{}
-
-		stack := []Coordinate{start}
-		var traverseable bool
-
-		for len(stack) > 0 {
-			from := stack[len(stack)-1]
-			stack = stack[:len(stack)-1]
-
-			visited[from] = struct{}{}
-
-			for _, direction := range []Coordinate{
-				{0, 1},
-				{0, -1},
-				{1, 0},
-				{-1, 0},
-			} {
-				to := Coordinate{
-					direction.X + from.X,
-					direction.Y + from.Y,
-				}
-
-				if _, ok := visited[to]; ok {
-					continue
-				}
-
-				if to.X < 0 || to.X > size || to.Y < 0 || to.Y > size {
-					continue
-				}
-
-				if _, ok := corrupted[to]; ok {
-					continue
-				}
-
-				if to.Equal(end) {
-					traverseable = true
-					break
-				} else {
-					stack = append(stack, to)
-				}
-			}
+	return steps, nil
+}
 
-		}
+func Part2(lines []string, size int) (Coordinate, error) {
+	coordinates, err := ParseCoordinates(lines)
+	if err != nil {
+		return Coordinate{}, err
+	}
 
-		if !traverseable {
-			return coordinate, nil
-		}
+	memory, err := NewMemory(coordinates, size)
+	if err != nil {
+		return Coordinate{}, err
+	}
+
+	blocking, _, ok := memory.Blocking()
+	if !ok {
+		return Coordinate{}, fmt.Errorf("no blocking coordinate found")
 	}
 
-	return Coordinate{}, fmt.Errorf("no blocking coordinate found")
+	return blocking, nil
 }
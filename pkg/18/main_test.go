@@ -1,105 +1,78 @@
 package main
 
 import (
-	"strconv"
+	"slices"
 	"strings"
 	"testing"
+
+	"github.com/max-nicholson/advent-of-code-2024/lib/gen"
+	"github.com/max-nicholson/advent-of-code-2024/lib/testutil"
 )
 
-func TestPart1(t *testing.T) {
-	for i, tc := range []struct {
-		input string
-		want  int
-		bytes int
-		size  int
-	}{
-		{
-			input: `5,4
-4,2
-4,5
-3,0
-2,1
-6,3
-2,4
-1,5
-0,6
-3,3
-2,6
-5,1
-1,2
-5,5
-2,5
-6,5
-1,4
-0,4
-6,4
-1,1
-6,1
-1,0
-0,5
-1,6
-2,0`,
-			want:  22,
-			bytes: 12,
-			size:  6,
-		},
-	} {
-		t.Run(strconv.Itoa(i), func(t *testing.T) {
-			got, err := Part1(strings.Split(tc.input, "\n"), tc.bytes, tc.size)
-			if err != nil {
-				t.Error(err)
+func TestExamples(t *testing.T) {
+	testutil.Golden(t, map[string]testutil.Part{
+		"part1": testutil.Lines(func(lines []string) (int, error) {
+			return Part1(lines, 12, 6)
+		}),
+		"part2": testutil.Lines(func(lines []string) (Coordinate, error) {
+			return Part2(lines, 6)
+		}),
+	})
+}
+
+func TestReachableMatchesShortestPath(t *testing.T) {
+	for seed := range uint64(10) {
+		const size = 12
+		coordinates, err := ParseCoordinates(strings.Split(gen.Day18(gen.New(seed), size+1), "\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// bytes landing on already corrupted tiles don't change anything
+		coordinates = slices.Concat(coordinates[:40], coordinates[:10], coordinates[40:])
+
+		memory, err := NewMemory(coordinates, size)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		blocking, at, ok := memory.Blocking()
+		if !ok || coordinates[at-1] != blocking {
+			t.Fatalf("Blocking() = %v at %d, %t", blocking, at, ok)
+		}
+
+		previous := 0
+		for step := range len(coordinates) + 1 {
+			steps, reachable := memory.ShortestPath(step)
+			if reachable != memory.Reachable(step) {
+				t.Fatalf("seed %d step %d: ShortestPath() reachable %t, Reachable() %t", seed, step, reachable, memory.Reachable(step))
 			}
-			if got != tc.want {
-				t.Errorf("got %d, want %d", got, tc.want)
+			if reachable != (step < at) {
+				t.Fatalf("seed %d step %d: reachable %t, but blocked at %d", seed, step, reachable, at)
 			}
-		})
+			if reachable && steps < previous {
+				t.Fatalf("seed %d step %d: path got shorter, from %d to %d", seed, step, previous, steps)
+			}
+			previous = steps
+		}
 	}
 }
 
-func TestPart2(t *testing.T) {
-	for i, tc := range []struct {
-		input string
-		want  Coordinate
-		size  int
-	}{
-		{
-			input: `5,4
-4,2
-4,5
-3,0
-2,1
-6,3
-2,4
-1,5
-0,6
-3,3
-2,6
-5,1
-1,2
-5,5
-2,5
-6,5
-1,4
-0,4
-6,4
-1,1
-6,1
-1,0
-0,5
-1,6
-2,0`,
-			want: Coordinate{6, 1},
-			size: 6,
-		},
-	} {
-		t.Run(strconv.Itoa(i), func(t *testing.T) {
-			got, err := Part2(strings.Split(tc.input, "\n"), tc.size)
-			if err != nil {
-				t.Error(err)
-			}
-			if !got.Equal(tc.want) {
-				t.Errorf("got %v, want %v", got, tc.want)
-			}
-		})
+func TestMemoryNeverBlocked(t *testing.T) {
+	memory, err := NewMemory([]Coordinate{{1, 0}, {1, 1}}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, ok := memory.Blocking(); ok {
+		t.Errorf("Blocking() found a blocking byte")
+	}
+	if steps, ok := memory.ShortestPath(2); !ok || steps != 4 || !memory.Reachable(2) {
+		t.Errorf("ShortestPath() = %d, %t, want 4, true", steps, ok)
+	}
+}
+
+func TestMemoryOutOfBounds(t *testing.T) {
+	if _, err := NewMemory([]Coordinate{{3, 0}}, 2); err == nil {
+		t.Errorf("NewMemory() want error for a byte outside the memory space")
 	}
 }
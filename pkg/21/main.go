@@ -444,30 +444,144 @@ func SequenceLength(start rune, end rune, depth int) int {
 	return min
 }
 
-func Part2(lines []string) (int, error) {
-	total := 0
+// MinSequenceLength is the length of the shortest sequence of button presses
+// on a directional keypad which types the code through the numeric keypad and
+// the given number of robot-operated directional keypads
+func (code Code) MinSequenceLength(robots int) int {
+	numericKeypad := NewNumericKeypad()
 
-	for _, line := range lines {
-		numericKeypad := NewNumericKeypad()
-		code := Code(line)
+	permutations := Permutations(string(code), numericKeypad)
+
+	min := math.MaxInt
+	for _, permutation := range permutations {
+		length := 0
 
-		permutations := Permutations(string(code), numericKeypad)
+		for _, pair := range Pairs("A" + permutation) {
+			a := pair[0]
+			b := pair[1]
 
-		min := math.MaxInt
-		for _, permutation := range permutations {
-			length := 0
+			length += SequenceLength(a, b, robots)
+		}
 
-			for _, pair := range Pairs("A" + permutation) {
-				a := pair[0]
-				b := pair[1]
+		min = lib.Min(min, length)
+	}
 
-				length += SequenceLength(a, b, 25)
+	return min
+}
+
+// MinSequenceLengthReference finds the same length as MinSequenceLength, by a
+// breadth-first search over every button the human can press and the
+// positions of every robot arm which results
+func (code Code) MinSequenceLengthReference(robots int) int {
+	numeric := [][]rune{
+		{'7', '8', '9'},
+		{'4', '5', '6'},
+		{'1', '2', '3'},
+		{' ', '0', 'A'},
+	}
+	directional := [][]rune{
+		{' ', '^', 'A'},
+		{'<', 'v', '>'},
+	}
+	deltas := map[rune]Position{
+		'^': {-1, 0},
+		'v': {1, 0},
+		'<': {0, -1},
+		'>': {0, 1},
+	}
+
+	type State struct {
+		typed int
+		// arms[0] is on the numeric keypad, the rest on directional keypads
+		arms string
+	}
+
+	encode := func(arms []Position) string {
+		b := make([]byte, 0, len(arms)*2)
+		for _, arm := range arms {
+			b = append(b, byte(arm.row), byte(arm.column))
+		}
+		return string(b)
+	}
+	decode := func(s string) []Position {
+		arms := make([]Position, len(s)/2)
+		for i := range arms {
+			arms[i] = Position{int(s[2*i]), int(s[2*i+1])}
+		}
+		return arms
+	}
+
+	arms := make([]Position, robots+1)
+	arms[0] = Find('A', numeric)
+	for i := 1; i <= robots; i++ {
+		arms[i] = Find('A', directional)
+	}
+
+	start := State{0, encode(arms)}
+	presses := map[State]int{start: 0}
+	queue := []State{start}
+
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+
+		for _, button := range "^v<>A" {
+			arms := decode(state.arms)
+			typed := state.typed
+			valid := true
+
+			for level := robots; level >= 0; level-- {
+				keypad := directional
+				if level == 0 {
+					keypad = numeric
+				}
+
+				if delta, ok := deltas[button]; ok {
+					arm := Position{arms[level].row + delta.row, arms[level].column + delta.column}
+					if arm.row < 0 || arm.row >= len(keypad) || arm.column < 0 || arm.column >= len(keypad[0]) || keypad[arm.row][arm.column] == ' ' {
+						valid = false
+					}
+					arms[level] = arm
+					break
+				}
+
+				// A presses the button the arm is pointing at
+				button = keypad[arms[level].row][arms[level].column]
+				if level == 0 {
+					if button != rune(code[typed]) {
+						valid = false
+					}
+					typed += 1
+				}
+			}
+
+			if !valid {
+				continue
+			}
+
+			next := State{typed, encode(arms)}
+			if _, ok := presses[next]; ok {
+				continue
 			}
+			presses[next] = presses[state] + 1
 
-			min = lib.Min(min, length)
+			if typed == len(code) {
+				return presses[next]
+			}
+			queue = append(queue, next)
 		}
+	}
+
+	panic("unreachable")
+}
+
+func Part2(lines []string) (int, error) {
+	total := 0
+
+	for _, line := range lines {
+		code := Code(line)
 
-		total += code.Numeric() * min
+		total += code.Numeric() * code.MinSequenceLength(25)
 	}
 
 	return total, nil
@@ -1,33 +1,30 @@
 package main
 
 import (
-	"strconv"
-	"strings"
 	"testing"
+
+	"github.com/max-nicholson/advent-of-code-2024/lib/gen"
+	"github.com/max-nicholson/advent-of-code-2024/lib/testutil"
 )
 
-func TestPart1(t *testing.T) {
-	for i, tc := range []struct {
-		input string
-		want  int
-	}{
-		{
-			input: `029A
-980A
-179A
-456A
-379A`,
-			want: 126384,
-		},
-	} {
-		t.Run(strconv.Itoa(i), func(t *testing.T) {
-			got, err := Part1(strings.Split(tc.input, "\n"))
-			if err != nil {
-				t.Error(err)
-			}
-			if got != tc.want {
-				t.Errorf("got %d, want %d", got, tc.want)
+func TestExamples(t *testing.T) {
+	testutil.Golden(t, map[string]testutil.Part{
+		"part1": testutil.Lines(Part1),
+		"part2": testutil.Lines(Part2),
+	})
+}
+
+func TestMinSequenceLengthMatchesReference(t *testing.T) {
+	for seed := range uint64(20) {
+		code := Code(gen.Day21(gen.New(seed), 1))
+
+		for robots := 1; robots <= 4; robots++ {
+			want := code.MinSequenceLengthReference(robots)
+			got := code.MinSequenceLength(robots)
+
+			if got != want {
+				t.Fatalf("code %s with %d robots: got %d, want %d", code, robots, got, want)
 			}
-		})
+		}
 	}
 }
@@ -1,57 +1,14 @@
 package main
 
 import (
-	"strconv"
-	"strings"
 	"testing"
-)
 
-func TestPart1(t *testing.T) {
-	for i, tc := range []struct {
-		input string
-		want  int
-	}{
-		{
-			input: `1
-10
-100
-2024`,
-			want: 37327623,
-		},
-	} {
-		t.Run(strconv.Itoa(i), func(t *testing.T) {
-			got, err := Part1(strings.Split(tc.input, "\n"))
-			if err != nil {
-				t.Error(err)
-			}
-			if got != tc.want {
-				t.Errorf("got %d, want %d", got, tc.want)
-			}
-		})
-	}
-}
+	"github.com/max-nicholson/advent-of-code-2024/lib/testutil"
+)
 
-func TestPart2(t *testing.T) {
-	for i, tc := range []struct {
-		input string
-		want  int
-	}{
-		{
-			input: `1
-2
-3
-2024`,
-			want:  23,
-		},
-	} {
-		t.Run(strconv.Itoa(i), func(t *testing.T) {
-			got, err := Part2(strings.Split(tc.input, "\n"))
-			if err != nil {
-				t.Error(err)
-			}
-			if got != tc.want {
-				t.Errorf("got %d, want %d", got, tc.want)
-			}
-		})
-	}
+func TestExamples(t *testing.T) {
+	testutil.Golden(t, map[string]testutil.Part{
+		"part1": testutil.Lines(Part1),
+		"part2": testutil.Lines(Part2),
+	})
 }